@@ -2,32 +2,49 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"github.com/spf13/cobra"
 	"log"
+	"maps"
 	"os"
 	"scriptcheck/color"
 	"scriptcheck/format"
+	"scriptcheck/report"
 	"scriptcheck/runtime"
+	"slices"
+	"strings"
 )
 
 func newCheckCommand(options *runtime.Options) *cobra.Command {
 	checkCmd := &cobra.Command{
 		Use:   "check [pattern]",
 		Short: "Run shellcheck against scripts in pipeline yml files",
-		Long:  "Run shellcheck against scripts in pipeline yml files",
-		Args:  cobra.MinimumNArgs(1),
+		Long:  "Run shellcheck or another checker against scripts in pipeline yml files",
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, globPatterns []string) {
+			if options.Watch {
+				if err := runtime.WatchFiles(options, globPatterns); err != nil {
+					log.Printf("There was an error watching your files: %v", err)
+					os.Exit(exitError)
+				}
+				return
+			}
+
 			if err := runtime.CheckFiles(options, globPatterns); err != nil {
 				var scriptCheckError *runtime.ScriptCheckError
 				if errors.As(err, &scriptCheckError) {
 					log.Printf(
-						"Found %s issues, exiting...",
+						"Found %s issues%s, exiting...",
 						color.Color(scriptCheckError.ReportCount(), color.Bold),
+						formatLevelCounts(scriptCheckError.LevelCounts()),
 					)
-					os.Exit(1)
+					if scriptCheckError.FailCount() > 0 {
+						os.Exit(exitFailure)
+					}
+					os.Exit(exitBelowThreshold)
 				} else {
 					log.Println("There was an error checking your files...")
-					os.Exit(2)
+					os.Exit(exitError)
 				}
 			} else {
 				log.Printf("Successfully checked files!")
@@ -58,6 +75,142 @@ func newCheckCommand(options *runtime.Options) *cobra.Command {
 		"shellcheck arguments",
 	)
 
+	enumVarP(
+		checkCmd.Flags(),
+		runtime.CheckerTypes,
+		&options.Checker,
+		runtime.CheckerShellcheck,
+		"checker",
+		"",
+		"Checker used for all scripts, syntax runs the installed interpreters and parse requires no external tool",
+	)
+
+	checkCmd.Flags().StringToStringVar(
+		&options.DialectCheckers,
+		"dialect-checker",
+		map[string]string{},
+		"Checker used for scripts of a shell dialect, e.g. bash=syntax,sh=parse",
+	)
+
+	checkCmd.Flags().StringVar(
+		&options.CacheDir,
+		"cache-dir",
+		runtime.DefaultCacheDir(),
+		"Directory to cache shellcheck results of unchanged scripts in",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.NoCache,
+		"no-cache",
+		false,
+		"Disable caching of shellcheck results",
+	)
+
+	checkCmd.Flags().IntVar(
+		&options.CacheMaxSize,
+		"cache-max-size",
+		runtime.DefaultCacheMaxSize,
+		"Maximum size of the result cache in megabytes, least recently used results get evicted",
+	)
+
+	checkCmd.Flags().StringVar(
+		&options.ChangedSince,
+		"changed-since",
+		"",
+		"Only check scripts changed since the merge base of the git reference and report findings on changed lines",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.ChangedBlocks,
+		"changed-blocks",
+		false,
+		"Report all findings of scripts changed since the reference given by --changed-since",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.Watch,
+		"watch",
+		false,
+		"Keep running and re-check files and their local includes whenever they change",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.Fix,
+		"fix",
+		false,
+		"Apply fixes provided by shellcheck to the yaml files, only findings which could not be fixed get reported",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.Diff,
+		"diff",
+		false,
+		"Print the diff of all fixes provided by shellcheck instead of applying them",
+	)
+
+	checkCmd.Flags().StringVar(
+		&options.Profile,
+		"profile",
+		"",
+		fmt.Sprintf("Profile of rule levels, either a profile file or one of %s", strings.Join(slices.Sorted(maps.Keys(report.Profiles)), ", ")),
+	)
+
+	checkCmd.Flags().StringToStringVar(
+		&options.RuleLevels,
+		"rule-level",
+		map[string]string{},
+		"Level reported for a rule independent of its shellcheck level, e.g. SC2086=error,SC2034=info",
+	)
+
+	checkCmd.Flags().StringVar(
+		&options.FailOn,
+		"fail-on",
+		report.Levels[0],
+		fmt.Sprintf("Minimum level of findings failing the check with exit code %d, other findings exit with %d [options: %s]", exitFailure, exitBelowThreshold, strings.Join(report.Levels, ", ")),
+	)
+
+	checkCmd.Flags().StringToStringVar(
+		&options.FailOnJobs,
+		"fail-on-job",
+		map[string]string{},
+		"Minimum failing level for jobs matching a glob pattern, e.g. deploy*=info,test*=error",
+	)
+
+	checkCmd.Flags().StringToStringVar(
+		&options.FailOnFiles,
+		"fail-on-file",
+		map[string]string{},
+		"Minimum failing level for files matching a glob pattern, e.g. ci/deploy/**=warning",
+	)
+
+	checkCmd.Flags().StringVar(
+		&options.Baseline,
+		"baseline",
+		"",
+		"Baseline file of accepted findings, only findings missing from the baseline get reported",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.UpdateBaseline,
+		"update-baseline",
+		false,
+		"Write all findings into the baseline file instead of reporting them, defaults to "+runtime.DefaultBaselineFile,
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.UnusedSuppressions,
+		"unused-suppressions",
+		false,
+		"Report rules disabled by scriptcheck directives which do not suppress any finding",
+	)
+
+	checkCmd.Flags().BoolVar(
+		&options.RequireSuppressionReason,
+		"require-reason",
+		false,
+		"Require a justification (reason=\"...\") for every rule disabled by a scriptcheck directive",
+	)
+
 	formatOptions := []format.Format{format.StandardFormat, format.CodeQualityFormat, format.JsonFormat}
 	enumVarP(
 		checkCmd.PersistentFlags(),
@@ -71,3 +224,19 @@ func newCheckCommand(options *runtime.Options) *cobra.Command {
 
 	return checkCmd
 }
+
+// formatLevelCounts formats the number of findings per level, like " (2 error, 1 info)"
+func formatLevelCounts(levelCounts map[string]int) string {
+	counts := make([]string, 0, len(levelCounts))
+	for _, level := range slices.Backward(report.Levels) {
+		if count := levelCounts[level]; count > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", count, level))
+		}
+	}
+
+	if len(counts) == 0 {
+		return ""
+	}
+
+	return " (" + strings.Join(counts, ", ") + ")"
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"maps"
+	"os"
+	"scriptcheck/runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// flags which can not be defined by the configuration file
+var nonConfigFlags = []string{"help", "config", "no-config"}
+
+func newConfigCommand(options *runtime.Options) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show the configuration of scriptcheck",
+		Long:  "Show the configuration of scriptcheck combining flags and the configuration file",
+	}
+
+	printCmd := &cobra.Command{
+		Use:   "print [command] [flags]",
+		Short: "Print the effective configuration of a command",
+		Long:  "Print the effective configuration of a command, check per default, where flags take precedence over the configuration file",
+		// flags are parsed by the printed command
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if slices.Contains(args, "-h") || slices.Contains(args, "--help") {
+				_ = cmd.Help()
+				return
+			}
+
+			if err := printConfig(cmd, options, args); err != nil {
+				cmd.PrintErrln("Error:", err)
+				os.Exit(exitError)
+			}
+		},
+	}
+
+	configCmd.AddCommand(printCmd)
+
+	return configCmd
+}
+
+// printConfig prints the values of all flags of the command given by
+// the first argument after parsing the remaining arguments as its flags
+func printConfig(cmd *cobra.Command, options *runtime.Options, args []string) error {
+	name := "check"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	target := findConfigurableCommand(cmd.Root(), name)
+	if target == nil {
+		return fmt.Errorf("unknown command %s", name)
+	}
+
+	if err := target.ParseFlags(args); err != nil {
+		return err
+	}
+
+	if err := applyConfig(target, options); err != nil {
+		return err
+	}
+
+	values := make(yaml.MapSlice, 0)
+	commandValues := make(yaml.MapSlice, 0)
+	target.Flags().VisitAll(func(flag *pflag.Flag) {
+		if slices.Contains(nonConfigFlags, flag.Name) || flag.Deprecated != "" {
+			return
+		}
+
+		item := yaml.MapItem{Key: flag.Name, Value: flagValue(target.Flags(), flag)}
+		if target.LocalNonPersistentFlags().Lookup(flag.Name) != nil {
+			commandValues = append(commandValues, item)
+		} else {
+			values = append(values, item)
+		}
+	})
+
+	values = append(values,
+		yaml.MapItem{Key: target.Name(), Value: commandValues},
+		yaml.MapItem{Key: "include", Value: options.Include},
+		yaml.MapItem{Key: "overrides", Value: options.Overrides},
+		yaml.MapItem{Key: "profiles", Value: options.Profiles},
+	)
+
+	content, err := yaml.MarshalWithOptions(values, yaml.IndentSequence(true))
+	if err != nil {
+		return fmt.Errorf("unable to print configuration: %w", err)
+	}
+
+	if options.ConfigFile != "" {
+		cmd.Printf("# configuration file: %s\n", options.ConfigFile)
+	} else {
+		cmd.Println("# no configuration file")
+	}
+	cmd.Print(string(content))
+
+	return nil
+}
+
+// applyConfig searches the configuration file, unless disabled, and sets all
+// flags of the command which are not given to the values of the file
+func applyConfig(cmd *cobra.Command, options *runtime.Options) error {
+	if options.NoConfig {
+		return nil
+	}
+
+	if options.ConfigFile == "" {
+		file, err := runtime.FindConfig(".")
+		if err != nil || file == "" {
+			return err
+		}
+		options.ConfigFile = file
+	}
+
+	config, err := runtime.LoadConfig(options.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	values, err := configValues(cmd, config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration %s: %w", options.ConfigFile, err)
+	}
+
+	for name, value := range values {
+		flag := cmd.Flags().Lookup(name)
+		if flag.Changed {
+			continue
+		}
+
+		if err := setFlag(cmd.Flags(), name, value); err != nil {
+			return fmt.Errorf("invalid configuration %s of %s: %w", options.ConfigFile, name, err)
+		}
+	}
+
+	options.Include = config.Include
+	options.Exclude = append(options.Exclude, config.Exclude...)
+	options.Overrides = config.Overrides
+	options.Profiles = config.Profiles
+
+	return nil
+}
+
+// configValues returns the values of the configuration for the flags
+// of the command, values of the section of the command take precedence
+func configValues(cmd *cobra.Command, config *runtime.Config) (map[string]any, error) {
+	values := make(map[string]any)
+	var commandValues map[string]any
+	for key, value := range config.Values {
+		if command := findConfigurableCommand(cmd.Root(), key); command != nil {
+			section, isSection := value.(map[string]any)
+			if !isSection {
+				return nil, fmt.Errorf("expected flags of command %s", key)
+			}
+
+			for name := range section {
+				if !isConfigFlag(command, name) {
+					return nil, fmt.Errorf("unknown flag %s of command %s", name, key)
+				}
+			}
+
+			if command == cmd {
+				commandValues = section
+			}
+			continue
+		}
+
+		if !slices.ContainsFunc(cmd.Root().Commands(), func(command *cobra.Command) bool {
+			return isConfigFlag(command, key)
+		}) {
+			return nil, fmt.Errorf("unknown key %s", key)
+		}
+
+		if isConfigFlag(cmd, key) {
+			values[key] = value
+		}
+	}
+
+	maps.Copy(values, commandValues)
+
+	return values, nil
+}
+
+func findConfigurableCommand(root *cobra.Command, name string) *cobra.Command {
+	for _, command := range root.Commands() {
+		if command.Name() == name && command.Runnable() && command.Name() != "help" {
+			return command
+		}
+	}
+
+	return nil
+}
+
+func isConfigFlag(cmd *cobra.Command, name string) bool {
+	if slices.Contains(nonConfigFlags, name) {
+		return false
+	}
+
+	return cmd.Flags().Lookup(name) != nil || cmd.InheritedFlags().Lookup(name) != nil
+}
+
+// setFlag sets the flag to the value of the configuration, where
+// elements of lists and entries of maps get set one by one
+func setFlag(flags *pflag.FlagSet, name string, value any) error {
+	switch typedValue := value.(type) {
+	case []any:
+		for _, element := range typedValue {
+			if err := flags.Set(name, fmt.Sprint(element)); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(typedValue)) {
+			if err := flags.Set(name, fmt.Sprintf("%s=%v", key, typedValue[key])); err != nil {
+				return err
+			}
+		}
+	default:
+		return flags.Set(name, fmt.Sprint(typedValue))
+	}
+
+	return nil
+}
+
+// flagValue returns the typed value of the flag
+func flagValue(flags *pflag.FlagSet, flag *pflag.Flag) any {
+	switch flag.Value.Type() {
+	case "bool":
+		value, _ := strconv.ParseBool(flag.Value.String())
+		return value
+	case "int", "uint":
+		value, _ := strconv.Atoi(flag.Value.String())
+		return value
+	case "stringArray":
+		value, _ := flags.GetStringArray(flag.Name)
+		return value
+	case "stringToString":
+		value, _ := flags.GetStringToString(flag.Name)
+		return value
+	default:
+		return flag.Value.String()
+	}
+}
@@ -11,7 +11,7 @@ func newExtractCommand(options *runtime.Options) *cobra.Command {
 		Use:   "extract [pattern]",
 		Short: "Extract script blocks from pipeline yaml files",
 		Long:  "Extract script blocks from pipeline yaml files",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, globPatterns []string) {
 			if err := runtime.ExtractScripts(options, globPatterns); err != nil {
 				os.Exit(1)
@@ -5,11 +5,15 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
 func TestExtractCommand(t *testing.T) {
-	_, err := ExecuteCommand(rootCmd, "extract", "../dir/first_yaml.yml")
+	// scripts are written relative to the output directory
+	// including the relative path of their yaml file
+	output := filepath.Join(t.TempDir(), "scripts")
+	_, err := ExecuteCommand(rootCmd, "extract", "--output", output, "../dir/first_yaml.yml")
 	if err != nil {
 		t.Errorf("Did not expect an error")
 	}
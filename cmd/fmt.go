@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"scriptcheck/color"
+	"scriptcheck/runtime"
+)
+
+func newFmtCommand(options *runtime.Options) *cobra.Command {
+	fmtCmd := &cobra.Command{
+		Use:     "fmt [pattern]",
+		Short:   "Format scripts in pipeline yml files",
+		Long:    "Format scripts in pipeline yml files and write them back while preserving the yaml style of every script",
+		PreRunE: inputArgs(options),
+		Run: func(cmd *cobra.Command, globPatterns []string) {
+			if err := runtime.FormatFiles(options, globPatterns); err != nil {
+				var scriptCheckError *runtime.ScriptCheckError
+				if errors.As(err, &scriptCheckError) {
+					log.Printf(
+						"Found %s unformatted script(s), exiting...",
+						color.Color(scriptCheckError.ReportCount(), color.Bold),
+					)
+					os.Exit(exitFailure)
+				} else {
+					log.Println("There was an error formatting your files...")
+					os.Exit(exitError)
+				}
+			} else {
+				log.Printf("Successfully formatted files!")
+			}
+		},
+	}
+
+	fmtCmd.Flags().StringVarP(
+		&options.OutputFile,
+		"output",
+		"o",
+		runtime.StdoutOutput,
+		"output file to write the diff into",
+	)
+
+	fmtCmd.Flags().UintVarP(
+		&options.Indent,
+		"indent",
+		"i",
+		0,
+		"Number of spaces used for indentation, tabs are used per default",
+	)
+
+	fmtCmd.Flags().BoolVar(
+		&options.BinaryNextLine,
+		"binary-next-line",
+		false,
+		"Place binary operators like && and | at the start of continued lines",
+	)
+
+	fmtCmd.Flags().BoolVar(
+		&options.CaseIndent,
+		"case-indent",
+		false,
+		"Indent the patterns of case statements",
+	)
+
+	fmtCmd.Flags().BoolVar(
+		&options.Check,
+		"check",
+		false,
+		"Do not write any file, but exit with a non-zero status if a script is not formatted",
+	)
+
+	fmtCmd.Flags().BoolVar(
+		&options.Diff,
+		"diff",
+		false,
+		"Print the diff of all formatting changes instead of applying them",
+	)
+
+	return fmtCmd
+}
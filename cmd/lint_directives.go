@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"scriptcheck/color"
+	"scriptcheck/format"
+	"scriptcheck/runtime"
+)
+
+func newLintDirectivesCommand(options *runtime.Options) *cobra.Command {
+	lintCmd := &cobra.Command{
+		Use:     "lint-directives [pattern]",
+		Short:   "Validate scriptcheck directives in pipeline yml files",
+		Long:    "Validate scriptcheck directives in pipeline yml files and report unknown keys, malformed values or misplaced directives",
+		PreRunE: inputArgs(options),
+		Run: func(cmd *cobra.Command, globPatterns []string) {
+			if err := runtime.LintDirectives(options, globPatterns); err != nil {
+				var scriptCheckError *runtime.ScriptCheckError
+				if errors.As(err, &scriptCheckError) {
+					log.Printf(
+						"Found %s directive issues, exiting...",
+						color.Color(scriptCheckError.ReportCount(), color.Bold),
+					)
+					os.Exit(exitFailure)
+				} else {
+					log.Println("There was an error linting your files...")
+					os.Exit(exitError)
+				}
+			} else {
+				log.Printf("Successfully linted directives!")
+			}
+		},
+	}
+
+	lintCmd.Flags().StringVarP(
+		&options.OutputFile,
+		"output",
+		"o",
+		runtime.StdoutOutput,
+		"output file to write into",
+	)
+
+	lintCmd.Flags().BoolVar(
+		&options.RequireSuppressionReason,
+		"require-reason",
+		false,
+		"Require a justification (reason=\"...\") for every rule disabled by a scriptcheck directive",
+	)
+
+	formatOptions := []format.Format{format.StandardFormat, format.CodeQualityFormat, format.JsonFormat}
+	enumVarP(
+		lintCmd.Flags(),
+		formatOptions,
+		&options.Format,
+		format.StandardFormat,
+		"format",
+		"f",
+		"Format in which you want to print directive issues",
+	)
+
+	return lintCmd
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"maps"
+	"os"
+	"scriptcheck/report"
+	"scriptcheck/runtime"
+	"slices"
+	"strings"
+)
+
+func newLspCommand(options *runtime.Options) *cobra.Command {
+	lspCmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server over stdio",
+		Long:  "Run a language server over stdio publishing findings of open pipeline files as diagnostics, including code actions to fix or disable them",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runtime.ServeLanguageServer(options, os.Stdin, os.Stdout); err != nil {
+				log.Printf("There was an error running the language server: %v", err)
+				os.Exit(exitError)
+			}
+		},
+	}
+
+	lspCmd.Flags().StringVar(
+		&options.DefaultShell,
+		"default-shell",
+		"",
+		"Defines default shell dialect to use in case no shebang or scriptcheck directive is used. Per default NO dialect will get specified",
+	)
+
+	lspCmd.Flags().StringArrayVarP(
+		&options.ShellCheckArgs,
+		"args",
+		"a",
+		[]string{},
+		"shellcheck arguments",
+	)
+
+	enumVarP(
+		lspCmd.Flags(),
+		runtime.CheckerTypes,
+		&options.Checker,
+		runtime.CheckerShellcheck,
+		"checker",
+		"",
+		"Checker used for all scripts, syntax runs the installed interpreters and parse requires no external tool",
+	)
+
+	lspCmd.Flags().StringToStringVar(
+		&options.DialectCheckers,
+		"dialect-checker",
+		map[string]string{},
+		"Checker used for scripts of a shell dialect, e.g. bash=syntax,sh=parse",
+	)
+
+	lspCmd.Flags().StringVar(
+		&options.CacheDir,
+		"cache-dir",
+		runtime.DefaultCacheDir(),
+		"Directory to cache shellcheck results of unchanged scripts in",
+	)
+
+	lspCmd.Flags().BoolVar(
+		&options.NoCache,
+		"no-cache",
+		false,
+		"Disable caching of shellcheck results",
+	)
+
+	lspCmd.Flags().IntVar(
+		&options.CacheMaxSize,
+		"cache-max-size",
+		runtime.DefaultCacheMaxSize,
+		"Maximum size of the result cache in megabytes, least recently used results get evicted",
+	)
+
+	lspCmd.Flags().StringVar(
+		&options.Profile,
+		"profile",
+		"",
+		fmt.Sprintf("Profile of rule levels, either a profile file or one of %s", strings.Join(slices.Sorted(maps.Keys(report.Profiles)), ", ")),
+	)
+
+	lspCmd.Flags().StringToStringVar(
+		&options.RuleLevels,
+		"rule-level",
+		map[string]string{},
+		"Level reported for a rule independent of its shellcheck level, e.g. SC2086=error,SC2034=info",
+	)
+
+	return lspCmd
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"os"
 	"scriptcheck/reader"
@@ -9,9 +10,19 @@ import (
 
 var rootCmd = newRootCmd()
 
+// exit codes of commands reporting findings
+const (
+	// findings at or above the fail threshold
+	exitFailure = 1
+	// errors running scriptcheck
+	exitError = 2
+	// findings below the fail threshold only
+	exitBelowThreshold = 3
+)
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(2)
+		os.Exit(exitError)
 	}
 }
 
@@ -21,8 +32,30 @@ func newRootCmd() *cobra.Command {
 		Use:   "scriptcheck",
 		Short: "Simple utility cli for working with pipeline scripts",
 		Long:  "CLI allowing to check or extract inlined pipeline scripts",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the configuration of printed commands is applied when printing it
+			if cmd.DisableFlagParsing {
+				return nil
+			}
+
+			return applyConfig(cmd, options)
+		},
 	}
 
+	cmd.PersistentFlags().StringVar(
+		&options.ConfigFile,
+		"config",
+		"",
+		fmt.Sprintf("Configuration file, per default %s is searched up to the root of the repository", runtime.ConfigFileNames[0]),
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&options.NoConfig,
+		"no-config",
+		false,
+		"Do not read any configuration file",
+	)
+
 	cmd.PersistentFlags().BoolVar(
 		&options.Debug,
 		"verbose",
@@ -44,12 +77,45 @@ func newRootCmd() *cobra.Command {
 		"Whether to fail when no files got found",
 	)
 
-	cmd.PersistentFlags().BoolVar(
-		&options.ExperimentalFolding,
+	// folded blocks are always read according to the yaml spec
+	cmd.PersistentFlags().Bool(
 		"folding-transformation",
 		false,
 		"Whether to use custom folding, in order to improve position information",
 	)
+	_ = cmd.PersistentFlags().MarkDeprecated(
+		"folding-transformation",
+		"folded blocks are read according to the yaml spec including their positions",
+	)
+
+	cmd.PersistentFlags().IntVarP(
+		&options.Jobs,
+		"jobs",
+		"j",
+		0,
+		"Number of files decoded and shellcheck processes run concurrently, defaults to the number of CPUs",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&options.StdinFileName,
+		"stdin-filename",
+		"",
+		"Read yaml from stdin and report findings using the given file name, same as passing - as pattern",
+	)
+
+	cmd.PersistentFlags().StringArrayVar(
+		&options.Exclude,
+		"exclude",
+		[]string{},
+		"Glob pattern of files excluded from checking, combined with the excluded files of the configuration",
+	)
+
+	cmd.PersistentFlags().StringArrayVar(
+		&options.Preludes,
+		"prelude",
+		[]string{},
+		"Shared script prepended to every script, either a yaml anchor (*anchor), the before_script of a job (job:name) or a shell file",
+	)
 
 	typeOptions := []reader.PipelineType{reader.PipelineTypeGitlab}
 	enumVarP(
@@ -65,7 +131,25 @@ func newRootCmd() *cobra.Command {
 	cmd.AddCommand(
 		newCheckCommand(options),
 		newExtractCommand(options),
+		newFmtCommand(options),
+		newLintDirectivesCommand(options),
+		newSuppressionsCommand(options),
+		newConfigCommand(options),
+		newLspCommand(options),
 	)
 
 	return cmd
 }
+
+// inputArgs requires at least one pattern, unless yaml gets read from stdin
+// or the configuration includes files. It is run as pre run, such that
+// the configuration is applied before validating the arguments.
+func inputArgs(options *runtime.Options) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if options.StdinFileName != "" || len(options.Include) > 0 {
+			return nil
+		}
+
+		return cobra.MinimumNArgs(1)(cmd, args)
+	}
+}
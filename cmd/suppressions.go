@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"scriptcheck/runtime"
+)
+
+func newSuppressionsCommand(options *runtime.Options) *cobra.Command {
+	suppressionsCmd := &cobra.Command{
+		Use:     "suppressions [pattern]",
+		Short:   "List rules disabled by scriptcheck directives",
+		Long:    "List all rules disabled by scriptcheck directives grouped by rule, file and job",
+		PreRunE: inputArgs(options),
+		Run: func(cmd *cobra.Command, globPatterns []string) {
+			if err := runtime.ListSuppressions(options, globPatterns); err != nil {
+				os.Exit(exitError)
+			}
+		},
+	}
+
+	suppressionsCmd.Flags().StringVarP(
+		&options.OutputFile,
+		"output",
+		"o",
+		runtime.StdoutOutput,
+		"output file to write into",
+	)
+
+	return suppressionsCmd
+}
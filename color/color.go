@@ -31,7 +31,7 @@ func Color(input interface{}, color ...string) string {
 	case string:
 		s = c + v + Reset
 	default:
-		fmt.Printf("Unsupported type provided to Color func - %T\n", v)
+		s = c + fmt.Sprintf("%v", v) + Reset
 	}
 	return s
 }
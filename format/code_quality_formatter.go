@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
 	"scriptcheck/report"
 )
 
-type CodeQualityReportFormatter struct{}
+type CodeQualityReportFormatter struct {
+	array jsonArrayWriter
+}
 
 type codeClimateReport struct {
 	Description string `json:"description"`
@@ -18,28 +21,37 @@ type codeClimateReport struct {
 		Path  string `json:"path"`
 		Lines struct {
 			Begin int `json:"begin"`
+			End   int `json:"end"`
 		} `json:"lines"`
 	} `json:"location"`
 }
 
-func (f *CodeQualityReportFormatter) Format(reports []report.ScriptCheckReport) (string, error) {
-	codeClimateReports := make([]codeClimateReport, 0)
-	for _, scriptReport := range reports {
-		reportLine := fmt.Sprintf("%s/%s", scriptReport.Reason, scriptReport.Message)
+func (f *CodeQualityReportFormatter) Begin(writer io.Writer) error {
+	return f.array.begin(writer)
+}
+
+func (f *CodeQualityReportFormatter) Report(writer io.Writer, scriptReport report.ScriptCheckReport) error {
+	reportLine := fmt.Sprintf("%s/%s", scriptReport.Reason, scriptReport.Message)
 
-		codeClimateReport := codeClimateReport{}
-		codeClimateReport.Description = reportLine
-		codeClimateReport.CheckName = scriptReport.Reason
-		codeClimateReport.Fingerprint = uuid.New().String()
-		codeClimateReport.Location.Path = scriptReport.File
-		codeClimateReport.Location.Lines.Begin = scriptReport.Line
-		codeClimateReport.Severity = severityFromShellcheck(scriptReport.Level)
+	codeClimateReport := codeClimateReport{}
+	codeClimateReport.Description = reportLine
+	codeClimateReport.CheckName = scriptReport.Reason
+	codeClimateReport.Fingerprint = uuid.New().String()
+	codeClimateReport.Location.Path = scriptReport.File
+	codeClimateReport.Location.Lines.Begin = scriptReport.Line
+	codeClimateReport.Location.Lines.End = max(scriptReport.EndLine, scriptReport.Line)
+	codeClimateReport.Severity = severityFromShellcheck(scriptReport.Level)
 
-		codeClimateReports = append(codeClimateReports, codeClimateReport)
+	marshal, err := json.Marshal(codeClimateReport)
+	if err != nil {
+		return err
 	}
 
-	marshal, err := json.Marshal(codeClimateReports)
-	return string(marshal), err
+	return f.array.element(writer, marshal)
+}
+
+func (f *CodeQualityReportFormatter) End(writer io.Writer) error {
+	return f.array.end(writer)
 }
 
 /*
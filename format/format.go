@@ -2,6 +2,7 @@ package format
 
 import (
 	"fmt"
+	"io"
 	"scriptcheck/report"
 )
 
@@ -13,19 +14,68 @@ const (
 	CodeQualityFormat Format = "code_quality"
 )
 
+// ShellCheckReportFormatter writes reports into a writer while they
+// get passed. Begin gets called once before the first report and End
+// once after the last report, allowing formats to open and close
+// enclosing structures or to flush buffered reports.
 type ShellCheckReportFormatter interface {
-	Format(reports []report.ScriptCheckReport) (string, error)
+	Begin(writer io.Writer) error
+	Report(writer io.Writer, report report.ScriptCheckReport) error
+	End(writer io.Writer) error
 }
 
-func NewFormatter(format Format) ShellCheckReportFormatter {
+func NewFormatter(format Format) (ShellCheckReportFormatter, error) {
 	switch format {
 	case CodeQualityFormat:
-		return &CodeQualityReportFormatter{}
+		return &CodeQualityReportFormatter{}, nil
 	case JsonFormat:
-		return &JsonFormatter{}
+		return &JsonFormatter{}, nil
 	case StandardFormat:
-		return &PrettyFormatter{}
+		return &PrettyFormatter{}, nil
 	}
 
-	panic(fmt.Sprintf("Unknown format %s", format))
+	return nil, fmt.Errorf("unknown format %s", format)
+}
+
+// WriteReports writes all given reports at once using the formatter
+func WriteReports(formatter ShellCheckReportFormatter, writer io.Writer, reports []report.ScriptCheckReport) error {
+	if err := formatter.Begin(writer); err != nil {
+		return err
+	}
+
+	for _, scriptReport := range reports {
+		if err := formatter.Report(writer, scriptReport); err != nil {
+			return err
+		}
+	}
+
+	return formatter.End(writer)
+}
+
+// jsonArrayWriter writes elements of a json array one by one
+type jsonArrayWriter struct {
+	elementCount int
+}
+
+func (w *jsonArrayWriter) begin(writer io.Writer) error {
+	w.elementCount = 0
+	_, err := io.WriteString(writer, "[")
+	return err
+}
+
+func (w *jsonArrayWriter) element(writer io.Writer, element []byte) error {
+	if w.elementCount > 0 {
+		if _, err := io.WriteString(writer, ","); err != nil {
+			return err
+		}
+	}
+	w.elementCount++
+
+	_, err := writer.Write(element)
+	return err
+}
+
+func (w *jsonArrayWriter) end(writer io.Writer) error {
+	_, err := io.WriteString(writer, "]")
+	return err
 }
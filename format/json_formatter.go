@@ -2,15 +2,26 @@ package format
 
 import (
 	"encoding/json"
+	"io"
 	"scriptcheck/report"
 )
 
-type JsonFormatter struct{}
+type JsonFormatter struct {
+	array jsonArrayWriter
+}
+
+func (f *JsonFormatter) Begin(writer io.Writer) error {
+	return f.array.begin(writer)
+}
 
-func (f *JsonFormatter) Format(reports []report.ScriptCheckReport) (string, error) {
-	if bytes, err := json.Marshal(reports); err != nil {
-		return "", err
+func (f *JsonFormatter) Report(writer io.Writer, scriptReport report.ScriptCheckReport) error {
+	if bytes, err := json.Marshal(scriptReport); err != nil {
+		return err
 	} else {
-		return string(bytes), nil
+		return f.array.element(writer, bytes)
 	}
 }
+
+func (f *JsonFormatter) End(writer io.Writer) error {
+	return f.array.end(writer)
+}
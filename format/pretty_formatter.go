@@ -3,56 +3,78 @@ package format
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"scriptcheck/color"
 	"scriptcheck/report"
 	"strings"
 )
 
+// PrettyFormatter groups consecutive reports of the same line,
+// thus only the reports of the current line get buffered
 type PrettyFormatter struct {
+	lineReports []report.ScriptCheckReport
 }
 
-func (f *PrettyFormatter) Format(reports []report.ScriptCheckReport) (string, error) {
-	builder := new(strings.Builder)
+func (f *PrettyFormatter) Begin(io.Writer) error {
+	f.lineReports = nil
+	return nil
+}
 
-	fileReports := make(map[string]map[int][]report.ScriptCheckReport)
-	for _, scriptReport := range reports {
-		if lineMap, exists := fileReports[scriptReport.File]; !exists {
-			lineMap := make(map[int][]report.ScriptCheckReport)
-			lineMap[scriptReport.Line] = append(lineMap[scriptReport.Line], scriptReport)
-			fileReports[scriptReport.File] = lineMap
-		} else {
-			lineMap[scriptReport.Line] = append(lineMap[scriptReport.Line], scriptReport)
+func (f *PrettyFormatter) Report(writer io.Writer, scriptReport report.ScriptCheckReport) error {
+	if len(f.lineReports) > 0 {
+		current := f.lineReports[0]
+		if current.File != scriptReport.File || current.Line != scriptReport.Line {
+			if err := f.flush(writer); err != nil {
+				return err
+			}
 		}
 	}
 
-	for file, reports := range fileReports {
-		for line, lineReports := range reports {
-			f.appendGroupedReport(builder, file, line, lineReports)
-		}
+	f.lineReports = append(f.lineReports, scriptReport)
+	return nil
+}
+
+func (f *PrettyFormatter) End(writer io.Writer) error {
+	return f.flush(writer)
+}
+
+func (f *PrettyFormatter) flush(writer io.Writer) error {
+	if len(f.lineReports) == 0 {
+		return nil
 	}
 
-	/*
-		builder.WriteString("\n TEST CURRENT \n")
-		for _, report := range reports {
-			f.appendReport(builder, report)
-		}
-	*/
+	builder := new(strings.Builder)
+	f.appendGroupedReport(builder, f.lineReports[0].File, f.lineReports[0].Line, f.lineReports)
+	f.lineReports = nil
 
-	return builder.String(), nil
+	_, err := io.WriteString(writer, builder.String())
+	return err
 }
 
 func (f *PrettyFormatter) appendGroupedReport(builder *strings.Builder, file string, line int, reports []report.ScriptCheckReport) {
 	builder.WriteString(color.Color(fmt.Sprintf("In %s line %d:", file, line), color.Bold))
 	builder.WriteString("\n")
-	builder.WriteString(f.getLine(reports[0]) + "\n")
+	scriptLine := f.getLine(reports[0])
+	if scriptLine != "" {
+		builder.WriteString(scriptLine + "\n")
+	}
 
 	informationList := make([]string, 0)
 	for _, scriptReport := range reports {
-		builder.WriteString(f.formatReportLine(scriptReport))
-		informationList = append(
-			informationList,
-			fmt.Sprintf("https://www.shellcheck.net/wiki/%s -- %s", scriptReport.Reason, scriptReport.Message),
-		)
+		builder.WriteString(f.formatReportLine(scriptReport, scriptLine != ""))
+
+		// only shellcheck reports provide further information
+		if strings.HasPrefix(scriptReport.Reason, "SC") {
+			informationList = append(
+				informationList,
+				fmt.Sprintf("https://www.shellcheck.net/wiki/%s -- %s", scriptReport.Reason, scriptReport.Message),
+			)
+		}
+	}
+
+	if len(informationList) == 0 {
+		builder.WriteString("\n")
+		return
 	}
 
 	builder.WriteString("For more information:\n")
@@ -71,13 +93,23 @@ func (f *PrettyFormatter) appendReport(builder *strings.Builder, report ScriptCh
 }
 */
 
-func (f *PrettyFormatter) formatReportLine(report report.ScriptCheckReport) string {
+// formatReportLine marks the columns of the report, which refer to the
+// printed script line or to the yaml file if no script line is printed
+func (f *PrettyFormatter) formatReportLine(report report.ScriptCheckReport, isScriptLine bool) string {
 	levelColor := f.getLevelColor(report.Level)
 
-	prefix := strings.Repeat(" ", report.Column-1)
+	column, endColumn := report.Column, report.EndColumn
+	if isScriptLine {
+		column, endColumn = report.Report.Column, report.Report.EndColumn
+		if report.Report.EndLine > report.Report.Line {
+			endColumn = column
+		}
+	}
+
+	prefix := strings.Repeat(" ", max(column-1, 0))
 	var marker string
-	if report.EndColumn-report.Column > 2 {
-		marker = "^" + strings.Repeat("-", report.EndColumn-report.Column-2) + "^"
+	if endColumn-column > 2 {
+		marker = "^" + strings.Repeat("-", endColumn-column-2) + "^"
 	} else {
 		marker = "^--"
 	}
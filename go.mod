@@ -8,6 +8,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/spf13/cobra v1.10.2
 	github.com/spf13/pflag v1.0.10
+	mvdan.cc/sh/v3 v3.12.0
 )
 
 require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,296 @@
+// Package scriptcheck checks and extracts the scripts of pipeline files.
+//
+// Neither function of the package writes any output, logs, or exits the
+// process. Problems are returned as errors, where inputs which are no valid
+// yaml return a *ParseError, unresolvable files or preludes a
+// *ResolutionError and failing checkers a *CheckerError. Other errors
+// describe an invalid configuration or the error of the done context.
+package scriptcheck
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"scriptcheck/runtime"
+)
+
+// Config configures checking and extracting scripts, the zero
+// value checks gitlab pipelines using shellcheck without cache
+type Config struct {
+	// pipeline type of all inputs, gitlab per default
+	PipelineType string
+
+	// shell dialect of scripts without shebang or shell directive
+	DefaultShell string
+
+	// references of shared scripts prepended to every script, either a
+	// yaml anchor (*anchor), the before_script of a job (job:name) or a file
+	Preludes []string
+
+	// checker of all scripts, shellcheck per default, and checkers
+	// overriding it for shell dialects, like bash=syntax
+	Checker         string
+	DialectCheckers map[string]string
+
+	// arguments passed to shellcheck without leading dashes, like severity=warning
+	ShellcheckArgs []string
+
+	// profile of rule levels, either a built-in profile or
+	// a profile file, and levels of single rules
+	Profile    string
+	RuleLevels map[string]string
+
+	// number of concurrently running checker processes, defaults to the number of CPUs
+	Jobs int
+
+	// directory caching shellcheck results, the cache is disabled
+	// unless given, and its maximum size in megabytes
+	CacheDir     string
+	CacheMaxSize int
+
+	// logger receiving progress and debug messages, nothing is logged unless given
+	Logger *log.Logger
+	Debug  bool
+}
+
+// Input is a pipeline file given by its name and optionally its content
+type Input struct {
+	// name of the file, which is used as file of all findings
+	Name string
+
+	// content of the file, the file is read in case no content is given
+	Content []byte
+}
+
+// Files returns the inputs reading the given files
+func Files(names ...string) []Input {
+	inputs := make([]Input, 0, len(names))
+	for _, name := range names {
+		inputs = append(inputs, Input{Name: name})
+	}
+
+	return inputs
+}
+
+// Level is the level of a finding
+type Level string
+
+const (
+	LevelStyle   Level = "style"
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+)
+
+// Finding is a violation found inside a script
+type Finding struct {
+	// file, job and section of the script and the path inside the yaml file
+	File    string
+	Job     string
+	Section string
+	Path    string
+
+	// shellcheck code prefixed by SC, like SC2086,
+	// or the reason provided by another checker
+	Rule    string
+	Level   Level
+	Message string
+
+	// range of the finding inside the yaml file, where lines and columns
+	// start at 1 and the end column points to the position following it
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+
+	// whether shellcheck provides a fix of the finding
+	Fixable bool
+}
+
+// Result contains the findings of all checked scripts
+type Result struct {
+	// number of checked scripts
+	Scripts int
+
+	// findings sorted by file and position
+	Findings []Finding
+}
+
+// Script is a script read from a pipeline file
+type Script struct {
+	// name of the script, which is unique within its file
+	Name string
+
+	File    string
+	Job     string
+	Section string
+	Path    string
+	Shell   string
+
+	// line of the script inside the yaml file
+	Line int
+
+	// content of the script prefixed by its shell
+	// directive and prelude, as passed to the checkers
+	Content string
+}
+
+// ParseError is returned for inputs which are no valid yaml
+type ParseError struct {
+	File string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unable to parse file %s: %v", e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ResolutionError is returned for inputs and references
+// of scripts, like preludes, which can not be resolved
+type ResolutionError struct {
+	Reference string
+	Err       error
+}
+
+func (e *ResolutionError) Error() string {
+	return fmt.Sprintf("unable to resolve %s: %v", e.Reference, e.Err)
+}
+
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// CheckerError is returned in case a checker fails to check the
+// scripts, like shellcheck not being installed
+type CheckerError struct {
+	Err error
+}
+
+func (e *CheckerError) Error() string {
+	return fmt.Sprintf("unable to check scripts: %v", e.Err)
+}
+
+func (e *CheckerError) Unwrap() error {
+	return e.Err
+}
+
+// Check checks the scripts of all inputs and returns their findings,
+// checking stops once the context is done
+func Check(ctx context.Context, config Config, inputs []Input) (Result, error) {
+	options := newOptions(config)
+	scripts, err := runtime.DecodeInputs(ctx, options, newInputs(inputs))
+	if err != nil {
+		return Result{}, newError(err)
+	}
+
+	reports, err := runtime.CheckScripts(ctx, options, scripts)
+	if err != nil {
+		return Result{}, newError(err)
+	}
+
+	findings := make([]Finding, 0, len(reports))
+	for _, scriptReport := range reports {
+		findings = append(findings, newFinding(scriptReport))
+	}
+
+	return Result{Scripts: len(scripts), Findings: findings}, nil
+}
+
+// Extract returns the scripts of all inputs
+func Extract(ctx context.Context, config Config, inputs []Input) ([]Script, error) {
+	scripts, err := runtime.DecodeInputs(ctx, newOptions(config), newInputs(inputs))
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	extracted := make([]Script, 0, len(scripts))
+	for _, script := range scripts {
+		extracted = append(extracted, Script{
+			Name:    script.BlockName,
+			File:    script.FileName,
+			Job:     script.Job,
+			Section: script.Section,
+			Path:    script.Path,
+			Shell:   script.Shell,
+			Line:    script.StartPos,
+			Content: script.ScriptString(),
+		})
+	}
+
+	return extracted, nil
+}
+
+func newOptions(config Config) *runtime.Options {
+	options := runtime.NewOptions()
+	options.PipelineType = reader.PipelineType(cmp.Or(config.PipelineType, string(reader.PipelineTypeGitlab)))
+	options.DefaultShell = config.DefaultShell
+	options.Preludes = config.Preludes
+	options.Checker = runtime.CheckerType(cmp.Or(config.Checker, string(runtime.CheckerShellcheck)))
+	options.DialectCheckers = config.DialectCheckers
+	options.ShellCheckArgs = config.ShellcheckArgs
+	options.Profile = config.Profile
+	options.RuleLevels = config.RuleLevels
+	options.Jobs = config.Jobs
+	options.CacheDir = config.CacheDir
+	options.CacheMaxSize = config.CacheMaxSize
+	options.Debug = config.Debug
+
+	options.Logger = config.Logger
+	if options.Logger == nil {
+		options.Logger = log.New(io.Discard, "", 0)
+	}
+
+	return options
+}
+
+func newInputs(inputs []Input) []runtime.Input {
+	runtimeInputs := make([]runtime.Input, 0, len(inputs))
+	for _, input := range inputs {
+		runtimeInputs = append(runtimeInputs, runtime.Input{Name: input.Name, Content: input.Content})
+	}
+
+	return runtimeInputs
+}
+
+// newError returns the typed error of the package for errors of the runtime
+func newError(err error) error {
+	var parseError *reader.ParseError
+	var resolutionError *reader.ResolutionError
+	var checkerError *runtime.CheckerError
+	switch {
+	case errors.As(err, &parseError):
+		return &ParseError{File: parseError.File, Err: parseError.Err}
+	case errors.As(err, &resolutionError):
+		return &ResolutionError{Reference: resolutionError.Reference, Err: resolutionError.Err}
+	case errors.As(err, &checkerError):
+		return &CheckerError{Err: checkerError.Err}
+	default:
+		return err
+	}
+}
+
+func newFinding(scriptReport report.ScriptCheckReport) Finding {
+	return Finding{
+		File:      scriptReport.File,
+		Job:       scriptReport.Script.Job,
+		Section:   scriptReport.Script.Section,
+		Path:      scriptReport.Path,
+		Rule:      scriptReport.Reason,
+		Level:     Level(scriptReport.Level),
+		Message:   scriptReport.Message,
+		Line:      scriptReport.Line,
+		Column:    scriptReport.Column,
+		EndLine:   scriptReport.EndLine,
+		EndColumn: scriptReport.EndColumn,
+		Fixable:   scriptReport.Report.Fix != nil,
+	}
+}
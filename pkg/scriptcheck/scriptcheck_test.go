@@ -0,0 +1,109 @@
+package scriptcheck
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	config := Config{Checker: "parse"}
+
+	result, err := Check(context.Background(), config, []Input{
+		{Name: "valid.yml", Content: []byte("job:\n  script: echo a\n")},
+		{Name: "invalid.yml", Content: []byte("job:\n  script: echo 'a\n")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if result.Scripts != 2 {
+		t.Errorf("expected 2 scripts, got %d", result.Scripts)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("expected a single finding, got %v", result.Findings)
+	}
+	if finding := result.Findings[0]; finding.File != "invalid.yml" || finding.Job != "job" ||
+		finding.Level != LevelError || finding.Line != 2 {
+		t.Errorf("unexpected finding %+v", finding)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		config   Config
+		inputs   []Input
+		expected func(err error) bool
+	}{
+		{
+			"parse error",
+			context.Background(),
+			Config{Checker: "parse"},
+			[]Input{{Name: "test.yml", Content: []byte("job: [\n")}},
+			func(err error) bool { return errors.As(err, new(*ParseError)) },
+		},
+		{
+			"missing file",
+			context.Background(),
+			Config{Checker: "parse"},
+			Files(filepath.Join(t.TempDir(), "missing.yml")),
+			func(err error) bool { return errors.As(err, new(*ResolutionError)) },
+		},
+		{
+			"missing prelude",
+			context.Background(),
+			Config{Checker: "parse", Preludes: []string{"*missing"}},
+			[]Input{{Name: "test.yml", Content: []byte("job:\n  script: echo a\n")}},
+			func(err error) bool { return errors.As(err, new(*ResolutionError)) },
+		},
+		{
+			"unknown alias",
+			context.Background(),
+			Config{Checker: "parse"},
+			[]Input{{Name: "test.yml", Content: []byte("job:\n  script: *missing\n")}},
+			func(err error) bool { return errors.As(err, new(*ResolutionError)) },
+		},
+		{
+			"unknown pipeline type",
+			context.Background(),
+			Config{PipelineType: "unknown"},
+			nil,
+			func(err error) bool { return err != nil },
+		},
+		{
+			"canceled",
+			canceled,
+			Config{Checker: "parse"},
+			[]Input{{Name: "test.yml", Content: []byte("job:\n  script: echo a\n")}},
+			func(err error) bool { return errors.Is(err, context.Canceled) },
+		},
+	}
+
+	for _, test := range tests {
+		if _, err := Check(test.ctx, test.config, test.inputs); !test.expected(err) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	scripts, err := Extract(context.Background(), Config{}, []Input{
+		{Name: "test.yml", Content: []byte("job:\n  script:\n    - echo a\n")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if len(scripts) != 1 {
+		t.Fatalf("expected a single script, got %v", scripts)
+	}
+	if script := scripts[0]; script.Job != "job" || script.Section != "script" || script.Line != 3 {
+		t.Errorf("unexpected script %+v", script)
+	}
+}
@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"github.com/goccy/go-yaml/ast"
 )
@@ -8,6 +9,9 @@ import (
 type anchorWalker struct {
 	anchorNodeMap map[string]ast.Node
 	aliasValueMap aliasValueMap
+
+	// name of the first alias referencing an unknown anchor
+	unknownAlias string
 }
 
 func (v *anchorWalker) Visit(node ast.Node) ast.Visitor {
@@ -15,7 +19,9 @@ func (v *anchorWalker) Visit(node ast.Node) ast.Visitor {
 	case *ast.AliasNode:
 		aliasName := n.Value.GetToken().Value
 		if anchorNode, exists := v.anchorNodeMap[aliasName]; !exists {
-			panic(fmt.Sprintf("could not find alias %q", aliasName))
+			if v.unknownAlias == "" {
+				v.unknownAlias = aliasName
+			}
 		} else {
 			v.aliasValueMap[n] = anchorNode
 		}
@@ -26,3 +32,16 @@ func (v *anchorWalker) Visit(node ast.Node) ast.Visitor {
 
 	return v
 }
+
+// resolutionError returns a ResolutionError in case any
+// alias of the walked file references an unknown anchor
+func (v *anchorWalker) resolutionError(file string) error {
+	if v.unknownAlias == "" {
+		return nil
+	}
+
+	return &ResolutionError{
+		Reference: fmt.Sprintf("alias *%s in file %s", v.unknownAlias, file),
+		Err:       errors.New("anchor not found"),
+	}
+}
@@ -0,0 +1,74 @@
+package reader
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestBlockScalarConformance compares the scripts read from block scalars
+// with the values defined by the yaml spec, as produced by libyaml
+func TestBlockScalarConformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected []string
+	}{
+		{"folded basic", "job:\n  script: >\n    a\n    b\n", []string{"a b\n"}},
+		{"folded blank", "job:\n  script: >\n    a\n\n    b\n", []string{"a\nb\n"}},
+		{"folded two blank", "job:\n  script: >\n    a\n\n\n    b\n", []string{"a\n\nb\n"}},
+		{"folded more indented", "job:\n  script: >\n    a\n      b\n    c\n", []string{"a\n  b\nc\n"}},
+		{"folded more indented blank", "job:\n  script: >\n    a\n\n      b\n\n    c\n", []string{"a\n\n  b\n\nc\n"}},
+		{"folded strip", "job:\n  script: >-\n    a\n    b\n\n", []string{"a b"}},
+		{"folded keep", "job:\n  script: >+\n    a\n    b\n\n\nnext:\n  script: x\n", []string{"a b\n\n\n", "x"}},
+		{"folded clip trailing", "job:\n  script: >\n    a\n\n\n", []string{"a\n"}},
+		{"folded leading blank", "job:\n  script: >\n\n    a\n    b\n", []string{"\na b\n"}},
+		{"folded heredoc", "job:\n  script: >\n    cat <<EOF\n      line\n    EOF\n", []string{"cat <<EOF\n  line\nEOF\n"}},
+		{"folded tab line", "job:\n  script: >\n    a\n    \tb\n    c\n", []string{"a\n\tb\nc\n"}},
+		{"folded indicator", "job:\n  script: >2\n      a\n    b\n", []string{"  a\nb\n"}},
+		{"folded indicator chomp", "job:\n  script: >-2\n      a\n    b\n", []string{"  a\nb"}},
+		{"folded comment after", "job:\n  script: >\n    a\n    b\n  # comment\n  after_script: c\n", []string{"a b\n", "c"}},
+		{"folded seq", "job:\n  script:\n    - >\n      a\n      b\n    - >-\n      c\n\n      d\n", []string{"a b\n", "c\nd"}},
+		{"folded seq indicator", "job:\n  script:\n    - >1\n       a\n      b\n", []string{"  a\n b\n"}},
+		{"folded trailing spaces", "job:\n  script: >\n    a  \n    b\n", []string{"a   b\n"}},
+		{"folded whitespace line", "job:\n  script: >\n    a\n      \n    b\n", []string{"a\n  \nb\n"}},
+		{"folded empty short", "job:\n  script: >\n    a\n  \n    b\n", []string{"a\nb\n"}},
+		{"literal basic", "job:\n  script: |\n    a\n      b\n\n    c\n", []string{"a\n  b\n\nc\n"}},
+		{"literal keep", "job:\n  script: |+\n    a\n\n\n", []string{"a\n\n\n"}},
+		{"literal strip", "job:\n  script: |-\n    a\n\n", []string{"a"}},
+		{"literal indicator", "job:\n  script: |2\n      a\n    b\n", []string{"  a\nb\n"}},
+		{"literal leading blank", "job:\n  script: |\n\n    a\n", []string{"\na\n"}},
+		{"literal whitespace line", "job:\n  script: |\n    a\n      \n    b\n", []string{"a\n  \nb\n"}},
+		{"literal comment header", "job:\n  script: | # comment\n    a\n", []string{"a\n"}},
+		{"literal anchor", "job:\n  script: &x |\n    a\n", []string{"a\n"}},
+		{"literal compact", "job:\n  script:\n  - |\n    a\n  - >\n    b\n    c\n", []string{"a\n", "b c\n"}},
+		{"literal keep eof", "job:\n  script: |+\n    a\n\n", []string{"a\n\n"}},
+		{"literal strip empty", "job:\n  script: |-\n\nnext:\n  script: x\n", []string{"", "x"}},
+		{"folded spaced last", "job:\n  script: >\n    a\n      b\n", []string{"a\n  b\n"}},
+		{"folded spaced first", "job:\n  script: >1\n      a\n    b\n    c\n", []string{"   a\n b\n c\n"}},
+		{"folded keep spaced", "job:\n  script: >+\n    a\n      b\n\n", []string{"a\n  b\n\n"}},
+		{"folded blank between spaced", "job:\n  script: >\n      a\n\n      b\n", []string{"a\nb\n"}},
+		{"folded deep seq", "b:\n  script:\n    - >-\n        one\n        two\n", []string{"one two"}},
+		{"folded unicode", "job:\n  script: >\n    é\n    ü\n", []string{"é ü\n"}},
+		{"literal only blank", "job:\n  script: |\n\n\nnext:\n  script: x\n", []string{"", "x"}},
+		{"folded header tab indent", "job:\n  script: >\n    a\n    \tb\n\n    c\n", []string{"a\n\tb\n\nc\n"}},
+	}
+
+	decoder := newGitlabDecoder(false, "")
+	for _, test := range tests {
+		scripts, err := decoder.DecodeReader("test.yml", strings.NewReader(test.yaml))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+			continue
+		}
+
+		values := make([]string, 0, len(scripts))
+		for _, script := range scripts {
+			values = append(values, string(script.Script))
+		}
+
+		if !slices.Equal(values, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, values)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
-	"github.com/goccy/go-yaml/token"
 	"regexp"
 	"slices"
 	"strings"
@@ -26,16 +25,22 @@ var sections = []string{
 	"after_script",
 }
 
-func newGitlabDecoder(debug bool, defaultShell string, experimentalFolding bool) ScriptDecoder {
+// glob patterns of pipeline files by convention, the default
+// pipeline, included pipelines and ci templates
+var gitlabFilePatterns = []string{
+	"**/.gitlab-ci.{yml,yaml}",
+	"**/*.gitlab-ci.{yml,yaml}",
+	".gitlab/ci/**/*.{yml,yaml}",
+}
+
+func newGitlabDecoder(debug bool, defaultShell string) ScriptDecoder {
 	decoder := ScriptDecoder{
 		ScriptReader: gitlabScriptReader{
-			defaultShell:        defaultShell,
-			experimentalFolding: experimentalFolding,
+			defaultShell: defaultShell,
 		},
-		defaultShell:        defaultShell,
-		debug:               debug,
-		parser:              readScriptsFromNode,
-		experimentalFolding: experimentalFolding,
+		defaultShell: defaultShell,
+		debug:        debug,
+		parser:       readScriptsFromNode,
 	}
 
 	return decoder
@@ -46,20 +51,26 @@ type gitlabScriptReader struct {
 
 	defaultShell string
 
-	experimentalFolding bool
-
 	// currently looped document
 	document      *ast.DocumentNode
 	aliasValueMap aliasValueMap
+
+	// file or document level directive of the current document
+	documentDirective *ScriptDirective
 }
 
-func (r gitlabScriptReader) readScriptsForAst(file *ast.File, aliasValueMap aliasValueMap) ([]ScriptBlock, error) {
+func (r gitlabScriptReader) readScriptsForAst(
+	file *ast.File,
+	aliasValueMap aliasValueMap,
+	documentDirectives documentDirectiveMap,
+) ([]ScriptBlock, error) {
 	r.aliasValueMap = aliasValueMap
 	if len(file.Docs) > 1 {
 		r.document = file.Docs[1]
 	} else {
 		r.document = file.Docs[0]
 	}
+	r.documentDirective = documentDirectives[r.document]
 
 	// read script blocks from given document
 	return r.readFromDocument(file.Name)
@@ -108,8 +119,8 @@ func (r gitlabScriptReader) readScriptsFromJob(file, jobName string, node *ast.M
 		eValue := element.Value
 		if slices.Contains(sections, eKey) {
 			blockName := jobName + "_" + eKey
-			directive := scriptDirectiveFromComment(element.GetComment())
-			for i, script := range readScriptsFromNode(r.document, eValue, r.aliasValueMap, r.experimentalFolding) {
+			directive := mergeScriptDirectives(r.documentDirective, scriptDirectiveFromComment(element.GetComment()))
+			for i, script := range readScriptsFromNode(r.document, eValue, r.aliasValueMap) {
 				var elementName string
 				if i > 0 {
 					elementName = blockName + fmt.Sprintf("_%d", i)
@@ -125,6 +136,8 @@ func (r gitlabScriptReader) readScriptsFromJob(file, jobName string, node *ast.M
 					eValue,
 					directive,
 				)
+				scriptBlock.Job = jobName
+				scriptBlock.Section = eKey
 
 				scripts = append(scripts, scriptBlock)
 			}
@@ -138,14 +151,13 @@ func readScriptsFromNode(
 	document *ast.DocumentNode,
 	node ast.Node,
 	aliasValueMap aliasValueMap,
-	experimentalFolding bool,
 ) []ScriptNode {
 	switch vType := node.(type) {
 	case *ast.TagNode:
 		if vType.Start.Value == gitlabReferenceTag {
 			referencedNode := readNodeFromReference(document, vType)
 			if referencedNode != nil {
-				return readScriptsFromNode(document, *referencedNode, aliasValueMap, experimentalFolding)
+				return readScriptsFromNode(document, *referencedNode, aliasValueMap)
 			} else {
 				return nil
 			}
@@ -153,127 +165,92 @@ func readScriptsFromNode(
 			return nil
 		}
 	case *ast.AnchorNode:
-		return readScriptsFromNode(document, vType.Value, aliasValueMap, experimentalFolding)
+		return readScriptsFromNode(document, vType.Value, aliasValueMap)
 	case *ast.AliasNode:
 		if anchorValue, exists := aliasValueMap[vType]; !exists {
-			aliasName := vType.Value.GetToken().Value
-			panic(fmt.Sprintf("anchor %s not found!", aliasName))
+			// aliases of unknown anchors are rejected by the anchor walker
+			return nil
 		} else {
 			// directly return alias is processed recursively
 			if anchorValue == vType {
 				script := replaceJobInputReference(vType.Value.String())
 				pos := vType.GetToken().Position.Line
-				return []ScriptNode{{script, pos}}
+				return []ScriptNode{{Script: script, Line: pos}}
 			} else {
-				return readScriptsFromNode(document, anchorValue, aliasValueMap, experimentalFolding)
+				return readScriptsFromNode(document, anchorValue, aliasValueMap)
 			}
 		}
 	case *ast.SequenceNode:
+		sequenceDirective := sequenceScriptDirective(vType)
 		elements := make([]ScriptNode, 0)
-		for _, listElement := range vType.Values {
-			scripts := readScriptsFromNode(document, listElement, aliasValueMap, experimentalFolding)
-			elements = append(elements, scripts...)
+		for i, listElement := range vType.Values {
+			itemDirective := mergeScriptDirectives(sequenceDirective, sequenceItemDirective(vType, i))
+			scripts := readScriptsFromNode(document, listElement, aliasValueMap)
+			for _, script := range scripts {
+				script.Directive = mergeScriptDirectives(itemDirective, script.Directive)
+				elements = append(elements, script)
+			}
 		}
 		return elements
-	// currently we do not directly create the script
-	// for the literals value as in this case the
-	// position (line) seems to be off. So we use the
-	// literals position and increment it by 1 as yaml
-	// expects a line break
+	// the script of block scalars gets read again from the yaml
+	// source, which folds them according to the yaml spec. The
+	// position of the script is the line following the header.
 	case *ast.LiteralNode:
-		var scriptString string
-		if vType.Start.Type == token.FoldedType && experimentalFolding {
-			origin := strings.TrimFunc(vType.Value.GetToken().Origin, unicode.IsSpace)
-			scriptString = unfoldFoldedLiteral(origin)
-		} else {
-			scriptString = vType.Value.Value
-		}
-
-		script := replaceJobInputReference(scriptString)
+		script := replaceJobInputReference(vType.Value.Value)
 		pos := vType.Start.Position.Line + 1
-		return []ScriptNode{{script, pos}}
+		return []ScriptNode{{
+			Script:    script,
+			Line:      pos,
+			Directive: scriptDirectiveFromComment(vType.GetComment()),
+			node:      vType,
+		}}
 	case *ast.StringNode:
 		// transform gitlab specific input markers
 		script := replaceJobInputReference(vType.Value)
 		pos := vType.GetToken().Position.Line
-		return []ScriptNode{{script, pos}}
+		return []ScriptNode{{
+			Script:    script,
+			Line:      pos,
+			Directive: scriptDirectiveFromComment(vType.GetComment()),
+			node:      vType,
+		}}
 	default:
 		return nil
 	}
 }
 
-func unfoldFoldedLiteral(literal string) string {
-	sb := new(strings.Builder)
-	/*
-		lineScanner := bufio.NewScanner(strings.NewReader(literal))
-		for lineScanner.Scan() {
-			println("line", lineScanner.Text())
-		}
-	*/
-	lbc := token.DetectLineBreakCharacter(literal)
-	lines := strings.Split(literal, lbc)
-	for index, element := range lines {
-		trimmed := strings.TrimLeftFunc(element, unicode.IsSpace)
-		if strings.ContainsFunc(element, isNoWhitespace) {
-			// for comments do not append a trailing slash
-			if strings.HasPrefix(trimmed, "#") {
-				sb.WriteString(trimmed)
-			} else {
-				sb.WriteString(trimmed)
-				if index != len(lines)-1 {
-					sb.WriteString(" \\")
-				}
-			}
-
-			if index != len(lines)-1 {
-				sb.WriteString("\n")
-			}
-		} else {
-			sb.WriteString("\n")
-		}
+func replaceJobInputReference(script string) Script {
+	builder := new(strings.Builder)
+	offset := 0
+	for _, deletedRange := range inputReferenceRanges(script) {
+		builder.WriteString(script[offset:deletedRange[0]])
+		offset = deletedRange[1]
 	}
+	builder.WriteString(script[offset:])
 
-	return sb.String()
-}
-
-func isNoWhitespace(rune rune) bool {
-	return !unicode.IsSpace(rune)
+	return Script(builder.String())
 }
 
-func replaceJobInputReference(script string) Script {
-	transformedString := script
-	res := jobInputRegex.FindAllStringSubmatch(transformedString, -1)
-	for i := range res {
-		subMatch := res[i]
-		match := subMatch[1]
-		inputName := subMatch[2]
-
-		var transformedInput string
-		if isSurroundedBy(subMatch[0], "'") {
-			// for single quoted inputs only unwrap the input name
-			transformedInput = strings.TrimSpace(inputName)
-		} else {
-			// for double-quoted and unquoted inputs we transform the input
-			// name to an environment variable in order to force warnings
-			// about preventing globbing and word splitting
-			// todo: consider whether we should do this kind of transformation
-			//  as we could also replace it with the input name - in case someone
-			//  wants to use inputs as environment variables he should explicitly
-			//  declare them as such
-			env := strings.ToUpper(inputName)
-			env = "${" + strings.TrimSpace(env) + "}"
-			transformedInput = env
-			transformedInput = strings.TrimSpace(inputName)
-		}
-
-		transformedString = strings.Replace(transformedString, match, transformedInput, 1)
+// inputReferenceRanges returns the byte ranges of the input references
+// to remove, such that only the input name remains. Quotes surrounding
+// the reference are kept.
+//
+// todo: consider whether we should transform inputs into environment
+// variables in order to force warnings about globbing and word splitting
+func inputReferenceRanges(script string) [][2]int {
+	ranges := make([][2]int, 0)
+	for _, match := range jobInputRegex.FindAllStringSubmatchIndex(script, -1) {
+		referenceStart, referenceEnd := match[2], match[3]
+		nameStart, nameEnd := match[4], match[5]
+
+		name := script[nameStart:nameEnd]
+		nameStart += len(name) - len(strings.TrimLeftFunc(name, unicode.IsSpace))
+		nameEnd -= len(name) - len(strings.TrimRightFunc(name, unicode.IsSpace))
+
+		ranges = append(ranges, [2]int{referenceStart, nameStart}, [2]int{nameEnd, referenceEnd})
 	}
 
-	return Script(transformedString)
-}
-
-func isSurroundedBy(s string, element string) bool {
-	return strings.HasPrefix(s, element) && strings.HasSuffix(s, element)
+	return ranges
 }
 
 func pathFromSequence(node *ast.SequenceNode) *yaml.Path {
@@ -292,3 +269,36 @@ func readNodeFromReference(document *ast.DocumentNode, tag *ast.TagNode) *ast.No
 	pathNode, _ := pathString.FilterNode(document.Body)
 	return &pathNode
 }
+
+// gitlabLocalIncludes returns the local files included by the pipeline,
+// either given as string or using the local keyword. Remote files,
+// templates and files of other projects are ignored.
+func gitlabLocalIncludes(content []byte) []string {
+	var pipeline struct {
+		Include any `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(content, &pipeline); err != nil {
+		return nil
+	}
+
+	includes := pipeline.Include
+	if _, isList := includes.([]any); !isList {
+		includes = []any{includes}
+	}
+
+	files := make([]string, 0)
+	for _, include := range includes.([]any) {
+		switch typedInclude := include.(type) {
+		case string:
+			if !strings.Contains(typedInclude, "://") {
+				files = append(files, typedInclude)
+			}
+		case map[string]any:
+			if local, isLocal := typedInclude["local"].(string); isLocal {
+				files = append(files, local)
+			}
+		}
+	}
+
+	return files
+}
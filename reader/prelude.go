@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml/ast"
+	"os"
+	"slices"
+	"strings"
+)
+
+// prefix of prelude references to the before_script of a job
+const preludeJobPrefix = "job:"
+
+// prefix of prelude references to a yaml anchor
+const preludeAnchorPrefix = "*"
+
+// section of a job used as prelude
+const preludeJobSection = "before_script"
+
+// preludeResolver resolves references to shared scripts, which get
+// prepended to a script in order to provide context like function
+// definitions. Supported references are yaml anchors (*anchor), the
+// before_script of another job (job:name) and shell files.
+type preludeResolver struct {
+	file          *ast.File
+	parser        scriptParser
+	anchorNodeMap map[string]ast.Node
+	aliasValueMap aliasValueMap
+}
+
+// applyPreludes prepends the default preludes and all
+// preludes referenced by directives to the scripts
+func (r preludeResolver) applyPreludes(scripts []ScriptBlock, defaultPreludes []string) error {
+	for i, script := range scripts {
+		references := slices.Clone(defaultPreludes)
+		if script.directive != nil {
+			references = append(references, script.directive.Preludes()...)
+		}
+
+		preludes := make([]string, 0, len(references))
+		for _, reference := range references {
+			prelude, err := r.resolve(reference)
+			if err != nil {
+				return &ResolutionError{Reference: "prelude of script " + script.BlockName, Err: err}
+			}
+			preludes = append(preludes, prelude)
+		}
+
+		if len(preludes) > 0 {
+			scripts[i].prelude = Script(strings.Join(preludes, "\n"))
+		}
+	}
+
+	return nil
+}
+
+func (r preludeResolver) resolve(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, preludeAnchorPrefix):
+		anchorName := strings.TrimPrefix(reference, preludeAnchorPrefix)
+		if anchorNode, exists := r.anchorNodeMap[anchorName]; exists {
+			return r.scriptFromNode(r.documentOf(anchorNode), anchorNode), nil
+		}
+
+		return "", fmt.Errorf("could not find anchor %q", anchorName)
+	case strings.HasPrefix(reference, preludeJobPrefix):
+		jobName := strings.TrimPrefix(reference, preludeJobPrefix)
+		for _, doc := range r.file.Docs {
+			if sectionNode := findJobSection(doc, jobName, preludeJobSection); sectionNode != nil {
+				return r.scriptFromNode(doc, sectionNode), nil
+			}
+		}
+
+		return "", fmt.Errorf("could not find %s of job %q", preludeJobSection, jobName)
+	default:
+		content, err := os.ReadFile(reference)
+		if err != nil {
+			return "", fmt.Errorf("unable to read prelude file: %w", err)
+		}
+
+		return string(content), nil
+	}
+}
+
+func (r preludeResolver) scriptFromNode(document *ast.DocumentNode, node ast.Node) string {
+	scripts := r.parser(document, node, r.aliasValueMap)
+
+	lines := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		lines = append(lines, strings.TrimSuffix(string(script.Script), "\n"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// documentOf returns the document containing the given node
+func (r preludeResolver) documentOf(node ast.Node) *ast.DocumentNode {
+	line := node.GetToken().Position.Line
+	document := r.file.Docs[0]
+	for _, doc := range r.file.Docs {
+		if doc.Body != nil && doc.Body.GetToken().Position.Line <= line {
+			document = doc
+		}
+	}
+
+	return document
+}
+
+// findJobSection returns the value of the given section of
+// a job defined at the top level of the document
+func findJobSection(document *ast.DocumentNode, jobName, section string) ast.Node {
+	body, ok := document.Body.(*ast.MappingNode)
+	if !ok {
+		return nil
+	}
+
+	for _, jobNode := range body.Values {
+		if jobNode.Key.String() != jobName {
+			continue
+		}
+
+		jobValue := jobNode.Value
+		if anchor, isAnchor := jobValue.(*ast.AnchorNode); isAnchor {
+			jobValue = anchor.Value
+		}
+
+		if jobMapping, isMapping := jobValue.(*ast.MappingNode); isMapping {
+			for _, element := range jobMapping.Values {
+				if element.Key.String() == section {
+					return element.Value
+				}
+			}
+		}
+	}
+
+	return nil
+}
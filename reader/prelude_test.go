@@ -0,0 +1,39 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrelude(t *testing.T) {
+	scripts, err := newGitlabDecoder(false, "").DecodeFile("../dir/prelude.yml")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	for _, script := range scripts {
+		if script.BlockName != "deploy_script" {
+			continue
+		}
+
+		// directive, function definition and before_script of setup job
+		if script.HeaderLines() != 5 {
+			t.Errorf("expected 5 header lines, got %d", script.HeaderLines())
+		}
+
+		scriptLines := strings.Split(script.ScriptString(), "\n")
+		if scriptLines[script.HeaderLines()] != string(script.Script) {
+			t.Errorf("expected script after prelude, got %q", scriptLines[script.HeaderLines()])
+		}
+		return
+	}
+
+	t.Errorf("expected deploy_script to be decoded")
+}
+
+func TestUnknownPrelude(t *testing.T) {
+	decoder := newGitlabDecoder(false, "").WithPreludes([]string{"*unknown"})
+	if _, err := decoder.DecodeFile("../dir/prelude.yml"); err == nil {
+		t.Errorf("expected unknown anchor to fail")
+	}
+}
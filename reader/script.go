@@ -3,7 +3,10 @@ package reader
 import (
 	"fmt"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/token"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -15,16 +18,27 @@ func NewScriptBlock(
 	node ast.Node,
 	directive *ScriptDirective,
 ) ScriptBlock {
+	// directives attached to the script node itself
+	// take precedence over the given directive
+	directive = mergeScriptDirectives(directive, script.Directive)
+
+	path := node.GetPath()
+	pathKeys := pathKeysFromPath(path)
+
 	block := ScriptBlock{
 		FileName:  file,
 		BlockName: blockName,
 		Script:    script.Script,
-		Path:      node.GetPath(),
+		Path:      path,
 		Shell:     defaultShell,
 		directive: directive,
+		node:      script.node,
+		StartPos:  script.Line,
+	}
 
-		// Column:   position.Column,
-		StartPos: script.Line,
+	if len(pathKeys) > 0 {
+		block.Job = pathKeys[0]
+		block.Section = pathKeys[len(pathKeys)-1]
 	}
 
 	if directive != nil {
@@ -36,6 +50,44 @@ func NewScriptBlock(
 	return block
 }
 
+// pathKeysFromPath returns the mapping keys of the given yaml
+// path, while ignoring the indices of sequence elements
+func pathKeysFromPath(path string) []string {
+	keys := make([]string, 0)
+	rest := strings.TrimPrefix(path, "$")
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '[':
+			if end := strings.IndexByte(rest, ']'); end >= 0 {
+				rest = rest[end+1:]
+			} else {
+				rest = ""
+			}
+		case '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, "'") {
+				end := strings.IndexByte(rest[1:], '\'')
+				if end < 0 {
+					end = len(rest) - 1
+				}
+				keys = append(keys, rest[1:end+1])
+				rest = rest[min(end+2, len(rest)):]
+			} else {
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				keys = append(keys, rest[:end])
+				rest = rest[end:]
+			}
+		default:
+			rest = rest[1:]
+		}
+	}
+
+	return keys
+}
+
 type ScriptBlock struct {
 	FileName  string
 	BlockName string
@@ -43,11 +95,23 @@ type ScriptBlock struct {
 	Shell     string
 	Path      string
 
+	// name of the job and the section inside
+	// the job the script was read from
+	Job     string
+	Section string
+
 	directive *ScriptDirective
 
-	// todo: currently does not work as expected
-	//  as positional information seem to be incorrect
-	//  in some cases
+	// shared script prepended when checking the script
+	prelude Script
+
+	// scalar node the script was read from and the
+	// positions of the script inside the yaml file
+	node      ast.Node
+	sourceMap *SourceMap
+
+	// line of the script inside the yaml file and the column
+	// of its first character, in case the script can be mapped
 	Column   int
 	StartPos int
 }
@@ -64,6 +128,15 @@ func (d ScriptDirective) asShellcheckDirective(script ScriptBlock) string {
 		rulesString := strings.Join(d.DisabledRules(), ",")
 		directiveBuilder.WriteString(fmt.Sprintf(" disable=%s", rulesString))
 	}
+
+	if len(d.EnabledChecks()) > 0 {
+		checksString := strings.Join(d.EnabledChecks(), ",")
+		directiveBuilder.WriteString(fmt.Sprintf(" enable=%s", checksString))
+	}
+
+	for _, sourcePath := range d.SourcePaths() {
+		directiveBuilder.WriteString(fmt.Sprintf(" source-path=%s", sourcePath))
+	}
 	directiveBuilder.WriteString("\n")
 
 	return directiveBuilder.String()
@@ -71,6 +144,15 @@ func (d ScriptDirective) asShellcheckDirective(script ScriptBlock) string {
 
 func (script ScriptBlock) ScriptString() string {
 	builder := new(strings.Builder)
+	builder.WriteString(script.header())
+	builder.WriteString(string(script.Script))
+	return builder.String()
+}
+
+// header returns the shellcheck directive and the prelude
+// prepended to the script when checking it
+func (script ScriptBlock) header() string {
+	builder := new(strings.Builder)
 
 	if script.directive != nil {
 		builder.WriteString(script.directive.asShellcheckDirective(script))
@@ -78,10 +160,83 @@ func (script ScriptBlock) ScriptString() string {
 		builder.WriteString(fmt.Sprintf("# shellcheck shell=%s\n", script.Shell))
 	}
 
-	builder.WriteString(string(script.Script))
+	if len(script.prelude) > 0 {
+		builder.WriteString(string(script.prelude))
+		if !strings.HasSuffix(string(script.prelude), "\n") {
+			builder.WriteString("\n")
+		}
+	}
+
 	return builder.String()
 }
 
+// HeaderLines returns the number of lines prepended to the script
+// by ScriptString, which are not part of the yaml file
+func (script ScriptBlock) HeaderLines() int {
+	return strings.Count(script.header(), "\n")
+}
+
+// SourceMap returns the positions of the script inside the
+// yaml file, nil in case the script can not be mapped exactly
+func (script ScriptBlock) SourceMap() *SourceMap {
+	return script.sourceMap
+}
+
+// readSource reads the script from the given lines of its yaml file,
+// which maps every character of the script to its yaml position
+func (script *ScriptBlock) readSource(lines []string) {
+	if script.node == nil {
+		return
+	}
+
+	script.Script, script.sourceMap = readSourceScript(lines, script.node, script.Script)
+	if start, exists := script.sourceMap.Position(1, 1); exists {
+		script.Column = start.Column
+	}
+}
+
+// SourcePosition returns the yaml position of the given position inside
+// the checked script, which includes the lines added by the header
+func (script ScriptBlock) SourcePosition(line, column int) (SourcePosition, bool) {
+	return script.sourceMap.Position(line-script.HeaderLines(), column)
+}
+
+// EndLine returns the line of the last script line inside the yaml file
+func (script ScriptBlock) EndLine() int {
+	if end, exists := script.sourceMap.End(); exists {
+		return end.Line
+	}
+
+	return script.StartPos + strings.Count(strings.TrimSuffix(string(script.Script), "\n"), "\n")
+}
+
+// DirectiveLine returns the line of the sequence element or mapping key
+// the script was read from, a directive comment placed above this line
+// applies to the script
+func (script ScriptBlock) DirectiveLine() int {
+	if script.node == nil {
+		return script.StartPos
+	}
+
+	// anchors and tags may precede the scalar on the line of the key
+	tk := script.node.GetToken().Prev
+	for tk != nil {
+		switch {
+		case tk.Type == token.SequenceEntryType || tk.Type == token.MappingValueType:
+			return tk.Position.Line
+		case tk.Type == token.AnchorType || tk.Type == token.TagType || tk.Type == token.CommentType:
+			tk = tk.Prev
+		case tk.Prev != nil && tk.Prev.Type == token.AnchorType:
+			// name of the anchor
+			tk = tk.Prev
+		default:
+			tk = nil
+		}
+	}
+
+	return script.node.GetToken().Position.Line
+}
+
 func (script ScriptBlock) HasShell() bool {
 	return len(script.Shell) > 0
 }
@@ -90,6 +245,89 @@ func (script ScriptBlock) HasShellDirective() bool {
 	return script.directive != nil
 }
 
+// HasSourcePaths reports whether the script defines paths
+// to resolve sourced files
+func (script ScriptBlock) HasSourcePaths() bool {
+	return script.directive != nil && len(script.directive.SourcePaths()) > 0
+}
+
+// Suppressions returns all rules disabled for the script by a directive
+func (script ScriptBlock) Suppressions() []Suppression {
+	if script.directive == nil {
+		return nil
+	}
+
+	return script.directive.Suppressions()
+}
+
+// WithoutSuppressions returns a copy of the script where no rules get
+// disabled by any directive, while rules disabled by defaults remain
+// disabled. The minimum severity is removed, as it would hide findings.
+func (script ScriptBlock) WithoutSuppressions() ScriptBlock {
+	if script.directive == nil {
+		return script
+	}
+
+	disabledRules := slices.DeleteFunc(script.directive.DisabledRules(), func(rule string) bool {
+		return slices.ContainsFunc(script.directive.suppressions, func(suppression Suppression) bool {
+			return suppression.Rule == rule
+		})
+	})
+
+	directive := ScriptDirective{values: maps.Clone(script.directive.values)}
+	delete(directive.values, directiveDisable)
+	delete(directive.values, directiveSeverity)
+	if len(disabledRules) > 0 {
+		directive.values[directiveDisable] = []string{strings.Join(disabledRules, ",")}
+	}
+	script.directive = &directive
+
+	return script
+}
+
+// WithDefaults returns a copy of the script using the given shell, disabled
+// rules and severity, unless they are defined by a directive of the script
+func (script ScriptBlock) WithDefaults(shell string, disabledRules []string, severity string) ScriptBlock {
+	defaults := ScriptDirective{values: map[string][]string{}}
+	if shell != "" {
+		defaults.values[directiveShell] = []string{shell}
+	}
+	if len(disabledRules) > 0 {
+		defaults.values[directiveDisable] = []string{strings.Join(disabledRules, ",")}
+	}
+	if severity != "" {
+		defaults.values[directiveSeverity] = []string{severity}
+	}
+
+	if len(defaults.values) == 0 {
+		return script
+	}
+
+	// disabled rules of the defaults are no suppressions, as
+	// they are not disabled by a directive inside the yaml file
+	script.directive = mergeScriptDirectives(&defaults, script.directive)
+	if directiveShell := script.directive.ShellDirective(); directiveShell != "" {
+		script.Shell = directiveShell
+	}
+
+	return script
+}
+
+// IsIgnored reports whether the script got excluded from checking
+func (script ScriptBlock) IsIgnored() bool {
+	return script.directive != nil && script.directive.Ignored()
+}
+
+// Severity returns the minimum severity of reports for this
+// script, or an empty string in case every report is relevant
+func (script ScriptBlock) Severity() string {
+	if script.directive == nil {
+		return ""
+	}
+
+	return script.directive.Severity()
+}
+
 func (script ScriptBlock) OutputFileName() string {
 	sBuilder := new(strings.Builder)
 	extension := filepath.Ext(script.FileName)
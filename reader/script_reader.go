@@ -1,12 +1,16 @@
 package reader
 
 import (
+	"cmp"
 	"fmt"
 	"github.com/goccy/go-yaml/ast"
 	"github.com/goccy/go-yaml/parser"
+	"io"
 	"log"
+	"os"
 	"scriptcheck/color"
 	"slices"
+	"strings"
 )
 
 type PipelineType string
@@ -15,125 +19,343 @@ const (
 	PipelineTypeGitlab PipelineType = "gitlab"
 )
 
-func NewDecoder(pipelineType PipelineType, debug bool, defaultShell string, experimentalFolding bool) ScriptDecoder {
+// StdinFile is the file name denoting yaml read from stdin
+const StdinFile = "-"
+
+// file name of yaml read from stdin if no virtual name is given
+const defaultStdinName = "stdin"
+
+// StdinName returns the file name of yaml read from stdin,
+// which is the virtual file name if given
+func StdinName(name string) string {
+	return cmp.Or(name, defaultStdinName)
+}
+
+func NewDecoder(pipelineType PipelineType, debug bool, defaultShell string) (ScriptDecoder, error) {
+	switch pipelineType {
+	case PipelineTypeGitlab:
+		return newGitlabDecoder(debug, defaultShell), nil
+	}
+
+	return ScriptDecoder{}, fmt.Errorf("unknown pipeline type: %s", pipelineType)
+}
+
+// PipelineFilePatterns returns the glob patterns of files
+// containing pipelines of the given type by convention
+func PipelineFilePatterns(pipelineType PipelineType) []string {
+	switch pipelineType {
+	case PipelineTypeGitlab:
+		return gitlabFilePatterns
+	}
+
+	return nil
+}
+
+// LocalIncludes returns the patterns of local files included by the
+// pipeline, which are relative to the root of the repository
+func LocalIncludes(pipelineType PipelineType, content []byte) []string {
 	switch pipelineType {
 	case PipelineTypeGitlab:
-		return newGitlabDecoder(debug, defaultShell, experimentalFolding)
+		return gitlabLocalIncludes(content)
 	}
 
-	panic(fmt.Sprintf("unknown pipeline type: %s", pipelineType))
+	return nil
 }
 
 type aliasValueMap map[*ast.AliasNode]ast.Node
+
+// file or document level directive applying to every script of a document
+type documentDirectiveMap map[*ast.DocumentNode]*ScriptDirective
 type scriptParser func(
 	document *ast.DocumentNode,
 	node ast.Node,
 	aliasValueMap aliasValueMap,
-	experimentalFolding bool,
 ) []ScriptNode
 
 type ScriptNode struct {
 	Script Script
 	Line   int
+
+	// directive attached directly to the node the script
+	// was read from, e.g. a trailing comment or the head
+	// comment of a sequence item
+	Directive *ScriptDirective
+
+	// scalar node the script was read from
+	node ast.Node
 }
 
 type ScriptReader interface {
-	readScriptsForAst(file *ast.File, aliasValueMap aliasValueMap) ([]ScriptBlock, error)
+	readScriptsForAst(
+		file *ast.File,
+		aliasValueMap aliasValueMap,
+		documentDirectives documentDirectiveMap,
+	) ([]ScriptBlock, error)
 }
 
 type ScriptDecoder struct {
 	ScriptReader
 
-	experimentalFolding bool
-	defaultShell        string
-	debug               bool
+	defaultShell string
+	debug        bool
+	logger       *log.Logger
+
+	// preludes prepended to every script
+	preludes []string
+
+	// yaml read for the StdinFile and its virtual file name
+	stdin     io.Reader
+	stdinName string
 
 	parser scriptParser
 }
 
+// WithPreludes returns a decoder prepending the referenced
+// shared scripts to every decoded script
+func (d ScriptDecoder) WithPreludes(preludes []string) ScriptDecoder {
+	d.preludes = preludes
+	return d
+}
+
+// WithLogger returns a decoder logging debug messages
+// to the given logger instead of the standard logger
+func (d ScriptDecoder) WithLogger(logger *log.Logger) ScriptDecoder {
+	d.logger = logger
+	return d
+}
+
+// WithStdin returns a decoder reading the StdinFile from the given
+// reader, reporting its scripts using the given virtual file name
+func (d ScriptDecoder) WithStdin(name string, input io.Reader) ScriptDecoder {
+	d.stdin = input
+	d.stdinName = StdinName(name)
+	return d
+}
+
 func (d ScriptDecoder) DecodeFile(file string) ([]ScriptBlock, error) {
-	if astFile, err := readFile(file); err != nil {
+	if astFile, source, err := d.readFile(file); err != nil {
 		return nil, err
 	} else {
-		return d.decodeAstFile(astFile)
+		return d.decodeAstFile(astFile, source)
 	}
 }
 
-func (d ScriptDecoder) MergeAndDecode(files []string) ([]ScriptBlock, error) {
-	if mergedFile, err := mergeFiles(files); err != nil {
+// DecodeReader decodes the yaml of the given reader, the name
+// is used as file name of all decoded scripts
+func (d ScriptDecoder) DecodeReader(name string, input io.Reader) ([]ScriptBlock, error) {
+	if astFile, source, err := readSource(name, input); err != nil {
 		return nil, err
 	} else {
-		return d.decodeAstFile(mergedFile)
+		return d.decodeAstFile(astFile, source)
 	}
 }
 
-func (d ScriptDecoder) decodeAstFile(astFile *ast.File) ([]ScriptBlock, error) {
-	scriptBlocks := make([]ScriptBlock, 0)
+func (d ScriptDecoder) MergeAndDecode(files []string) ([]ScriptBlock, error) {
+	if mergedFile, err := d.mergeFiles(files); err != nil {
+		return nil, err
+	} else {
+		return d.decodeAstFile(mergedFile, nil)
+	}
+}
 
-	anchorWalker := &anchorWalker{
-		anchorNodeMap: make(map[string]ast.Node),
-		aliasValueMap: make(aliasValueMap),
+// decodeAstFile decodes the scripts of the file, the source of the file is
+// used to map the scripts back to the yaml and may be nil for merged files
+func (d ScriptDecoder) decodeAstFile(astFile *ast.File, source []byte) ([]ScriptBlock, error) {
+	// collect anchors and directives within a single traversal
+	visitor := newScriptCheckDirectiveVisitor()
+	if err := visitor.walkFile(astFile); err != nil {
+		return nil, err
 	}
 
-	// otherwise the current filter walker fails as body
-	// will be null for empty yaml files
-	for _, doc := range astFile.Docs {
-		if doc.Body != nil {
-			ast.Walk(anchorWalker, doc.Body)
-		}
+	documentDirectives := documentDirectivesFromFile(astFile)
+	readerScripts, err := d.readScriptsForAst(astFile, visitor.aliasValueMap, documentDirectives)
+	if err != nil {
+		return nil, err
 	}
 
-	readerScripts, err := d.readScriptsForAst(astFile, anchorWalker.aliasValueMap)
+	logger := cmp.Or(d.logger, log.Default())
 	if d.debug {
-		log.Printf(
+		logger.Printf(
 			"Extracted %s script(s) from file '%s'\n",
 			color.Color(len(readerScripts), color.Bold),
 			color.Color(astFile.Name, color.Bold),
 		)
 	}
 
-	directiveDecoder := newScriptCheckDirectiveDecoder(d)
-	directiveScripts, err := directiveDecoder.readScriptsForAst(astFile, anchorWalker.aliasValueMap)
-
+	directiveScripts := visitor.readScripts(astFile, d, documentDirectives)
 	if d.debug {
-		log.Printf(
+		logger.Printf(
 			"Extracted %s script(s) from directives for file '%s'\n",
 			color.Color(len(directiveScripts), color.Bold),
 			color.Color(astFile.Name, color.Bold),
 		)
 	}
 
-	if err != nil {
-		return nil, err
-	}
-
+	scriptBlocks := make([]ScriptBlock, 0, len(readerScripts)+len(directiveScripts))
 	scriptBlocks = append(scriptBlocks, readerScripts...)
-	for _, directiveScript := range directiveScripts {
-		contains := slices.ContainsFunc(scriptBlocks, func(block ScriptBlock) bool {
-			return directiveScript.FileName == block.FileName && directiveScript.Path == block.Path
-		})
 
-		if !contains {
+	index := newScriptIndex(readerScripts)
+	for _, directiveScript := range directiveScripts {
+		if !index.contains(directiveScript) {
 			scriptBlocks = append(scriptBlocks, directiveScript)
 		}
 	}
 
+	// remove scripts explicitly excluded by a directive
+	scriptBlocks = slices.DeleteFunc(scriptBlocks, ScriptBlock.IsIgnored)
+
+	if source != nil {
+		// the line break of the last line does not start another line
+		content := strings.ReplaceAll(string(source), "\r\n", "\n")
+		lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		for i := range scriptBlocks {
+			scriptBlocks[i].readSource(lines)
+		}
+	}
+
+	// keep a stable order by position inside the file
+	slices.SortStableFunc(scriptBlocks, func(a, b ScriptBlock) int {
+		return cmp.Compare(a.StartPos, b.StartPos)
+	})
+
+	resolver := preludeResolver{
+		file:          astFile,
+		parser:        d.parser,
+		anchorNodeMap: visitor.anchorNodeMap,
+		aliasValueMap: visitor.aliasValueMap,
+	}
+
+	if err := resolver.applyPreludes(scriptBlocks, d.preludes); err != nil {
+		return nil, err
+	}
+
 	return scriptBlocks, nil
 }
 
-func readFile(file string) (*ast.File, error) {
-	astFile, err := parser.ParseFile(file, parser.ParseComments, parser.AllowDuplicateMapKey())
+type scriptKey struct {
+	file string
+	path string
+	line int
+}
+
+// scriptIndex allows looking up whether a script was already read from the
+// same yaml node. Scripts of sequence elements are read as part of their
+// parent node, so an element is considered the same when its position
+// matches the one of a script read from the parent node.
+type scriptIndex struct {
+	paths     map[scriptKey]bool
+	positions map[scriptKey]bool
+}
+
+func newScriptIndex(scripts []ScriptBlock) scriptIndex {
+	index := scriptIndex{
+		paths:     make(map[scriptKey]bool, len(scripts)),
+		positions: make(map[scriptKey]bool, len(scripts)),
+	}
+
+	for _, script := range scripts {
+		index.paths[scriptKey{file: script.FileName, path: script.Path}] = true
+		index.positions[scriptKey{script.FileName, script.Path, script.StartPos}] = true
+	}
+
+	return index
+}
+
+func (i scriptIndex) contains(script ScriptBlock) bool {
+	if i.paths[scriptKey{file: script.FileName, path: script.Path}] {
+		return true
+	}
+
+	parentPath := script.Path
+	for strings.HasSuffix(parentPath, "]") {
+		if start := strings.LastIndexByte(parentPath, '['); start > 0 {
+			parentPath = parentPath[:start]
+		} else {
+			break
+		}
+
+		if i.positions[scriptKey{script.FileName, parentPath, script.StartPos}] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readFile parses the given file and returns its source as well,
+// the StdinFile gets read from the configured reader instead
+func (d ScriptDecoder) readFile(file string) (*ast.File, []byte, error) {
+	if file == StdinFile && d.stdin != nil {
+		return readSource(d.stdinName, d.stdin)
+	}
+
+	return readFile(file)
+}
+
+// ParseError is returned for yaml files which can not be parsed
+type ParseError struct {
+	File string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unable to parse file %s: %v", e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ResolutionError is returned for files and references
+// of scripts, like preludes, which can not be resolved
+type ResolutionError struct {
+	Reference string
+	Err       error
+}
+
+func (e *ResolutionError) Error() string {
+	return fmt.Sprintf("unable to resolve %s: %v", e.Reference, e.Err)
+}
+
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+func readFile(file string) (*ast.File, []byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, &ResolutionError{Reference: "file " + file, Err: err}
+	}
+
+	astFile, err := parser.ParseBytes(content, parser.ParseComments, parser.AllowDuplicateMapKey())
+	if err != nil {
+		return nil, nil, &ParseError{File: file, Err: err}
+	}
+	astFile.Name = file
+
+	return astFile, content, nil
+}
+
+// readSource parses the yaml of the given reader
+func readSource(name string, input io.Reader) (*ast.File, []byte, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read file %s: %w", name, err)
+	}
+
+	astFile, err := parser.ParseBytes(content, parser.ParseComments, parser.AllowDuplicateMapKey())
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse file %s: %w", file, err)
+		return nil, nil, &ParseError{File: name, Err: err}
 	}
+	astFile.Name = name
 
-	return astFile, nil
+	return astFile, content, nil
 }
 
-func mergeFiles(files []string) (*ast.File, error) {
+func (d ScriptDecoder) mergeFiles(files []string) (*ast.File, error) {
 	var mergedNode *ast.DocumentNode
 	for index, file := range files {
-		astFile, err := readFile(file)
+		astFile, _, err := d.readFile(file)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,31 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeStdin(t *testing.T) {
+	yaml := "job:\n  script:\n    - echo first\n    - echo second\n"
+	decoder := newGitlabDecoder(false, "").
+		WithStdin(".gitlab-ci.yml", strings.NewReader(yaml))
+
+	scripts, err := decoder.DecodeFile(StdinFile)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if len(scripts) != 2 {
+		t.Fatalf("expected 2 scripts, got %d", len(scripts))
+	}
+
+	for i, script := range scripts {
+		if script.FileName != ".gitlab-ci.yml" {
+			t.Errorf("expected virtual file name, got %s", script.FileName)
+		}
+
+		if script.StartPos != i+3 {
+			t.Errorf("expected script at line %d, got %d", i+3, script.StartPos)
+		}
+	}
+}
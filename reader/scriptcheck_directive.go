@@ -2,51 +2,336 @@ package reader
 
 import (
 	"github.com/goccy/go-yaml/ast"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 const scriptCheckPrefix = "scriptcheck"
 
-type ScriptDirective map[string]string
+// prefix of directives applying to a whole file or document
+const scriptCheckFilePrefix = "scriptcheck-file"
+
+// directive keys supported inside a scriptcheck directive
+const (
+	directiveShell      = "shell"
+	directiveDisable    = "disable"
+	directiveEnable     = "enable"
+	directiveIgnore     = "ignore"
+	directiveSkip       = "skip"
+	directiveSeverity   = "severity"
+	directiveSourcePath = "source-path"
+	directiveReason     = "reason"
+	directivePrelude    = "prelude"
+)
+
+// directive keys whose values get combined when
+// repeated or merged with another directive
+var directiveListKeys = []string{directiveDisable, directiveEnable, directiveSourcePath, directivePrelude}
+
+// ScriptDirective contains all values of a scriptcheck directive by key.
+// Keys might be repeated, so every key can hold multiple values.
+type ScriptDirective struct {
+	values map[string][]string
+
+	// rules disabled by this directive or any merged directive
+	suppressions []Suppression
+}
+
+// Suppression describes a single rule disabled by a directive
+type Suppression struct {
+	Rule   string
+	Reason string
+
+	// line of the directive comment disabling the rule
+	Line int
+}
 
 func scriptDirectiveFromString(dataString string) ScriptDirective {
-	data := strings.TrimPrefix(dataString, scriptCheckPrefix)
-	data = strings.TrimSpace(data)
-	markerParts := strings.Split(data, " ")
-
-	directives := map[string]string{}
-	for _, markerPart := range markerParts {
-		if len(markerPart) > 0 {
-			keyValue := strings.SplitN(markerPart, "=", 2)
-			key := keyValue[0]
-
-			var value string
-			if len(keyValue) > 1 {
-				value = keyValue[1]
+	return newScriptDirective(strings.TrimPrefix(dataString, scriptCheckPrefix))
+}
+
+func fileDirectiveFromString(dataString string) ScriptDirective {
+	return newScriptDirective(strings.TrimPrefix(dataString, scriptCheckFilePrefix))
+}
+
+func newScriptDirective(data string) ScriptDirective {
+	directive := ScriptDirective{values: map[string][]string{}}
+	for _, field := range splitDirectiveFields(data) {
+		keyValue := strings.SplitN(field, "=", 2)
+		key := keyValue[0]
+
+		var value string
+		if len(keyValue) > 1 {
+			value = keyValue[1]
+		}
+		directive.values[key] = append(directive.values[key], value)
+	}
+
+	for _, rule := range directive.DisabledRules() {
+		directive.suppressions = append(directive.suppressions, Suppression{
+			Rule:   rule,
+			Reason: directive.value(directiveReason),
+		})
+	}
+
+	return directive
+}
+
+// withLine sets the line of the directive comment for all suppressions
+func (d ScriptDirective) withLine(line int) ScriptDirective {
+	for i := range d.suppressions {
+		d.suppressions[i].Line = line
+	}
+
+	return d
+}
+
+// splitDirectiveFields splits the directive at whitespaces, while
+// keeping whitespaces inside single- or double-quoted values
+func splitDirectiveFields(data string) []string {
+	fields := make([]string, 0)
+	field := new(strings.Builder)
+	inField := false
+	var quote rune
+
+	for i := 0; i < len(data); i++ {
+		c := rune(data[i])
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == '"' && c == '\\' && i+1 < len(data):
+			i++
+			field.WriteByte(data[i])
+		case quote != 0:
+			field.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inField = true
+		case unicode.IsSpace(c):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
 			}
-			directives[key] = value
+		default:
+			field.WriteRune(c)
+			inField = true
 		}
 	}
 
-	return directives
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+// value returns the last value defined for the given key
+func (d ScriptDirective) value(key string) string {
+	if values := d.values[key]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+
+	return ""
+}
+
+// listValue returns all comma separated values defined for the given key.
+// Elements prefixed with a dash remove a previously defined element, which
+// allows reverting values of an outer directive.
+func (d ScriptDirective) listValue(key string) []string {
+	list := make([]string, 0)
+	for _, value := range d.values[key] {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.TrimSpace(element)
+			if removed, isRemoval := strings.CutPrefix(element, "-"); isRemoval {
+				list = slices.DeleteFunc(list, func(e string) bool { return e == removed })
+			} else if element != "" && !slices.Contains(list, element) {
+				list = append(list, element)
+			}
+		}
+	}
+
+	return list
 }
 
 func (d ScriptDirective) ShellDirective() string {
-	return d["shell"]
+	return d.value(directiveShell)
 }
 
 func (d ScriptDirective) DisabledRules() []string {
-	disabled, ok := d["disable"]
-	if ok {
-		return strings.Split(disabled, ",")
-	} else {
-		return []string{}
+	return d.listValue(directiveDisable)
+}
+
+// EnabledChecks returns the optional shellcheck checks to enable
+func (d ScriptDirective) EnabledChecks() []string {
+	return d.listValue(directiveEnable)
+}
+
+// SourcePaths returns the paths used by shellcheck to resolve sourced files
+func (d ScriptDirective) SourcePaths() []string {
+	return d.listValue(directiveSourcePath)
+}
+
+// Preludes returns the references of shared scripts to prepend
+func (d ScriptDirective) Preludes() []string {
+	return d.listValue(directivePrelude)
+}
+
+// Severity returns the minimum severity a report needs to have
+func (d ScriptDirective) Severity() string {
+	return d.value(directiveSeverity)
+}
+
+// Ignored reports whether the script should be excluded from checking
+func (d ScriptDirective) Ignored() bool {
+	_, ignore := d.values[directiveIgnore]
+	_, skip := d.values[directiveSkip]
+	return ignore || skip
+}
+
+// mergeScriptDirectives merges the given directives, where values of
+// the override take precedence over the base. Values of list keys
+// like disabled rules of both directives get combined.
+func mergeScriptDirectives(base, override *ScriptDirective) *ScriptDirective {
+	if base == nil {
+		return override
+	} else if override == nil {
+		return base
 	}
+
+	merged := ScriptDirective{values: map[string][]string{}}
+	for key, values := range base.values {
+		merged.values[key] = slices.Clone(values)
+	}
+
+	for key, values := range override.values {
+		if slices.Contains(directiveListKeys, key) {
+			merged.values[key] = append(merged.values[key], values...)
+		} else {
+			merged.values[key] = slices.Clone(values)
+		}
+	}
+
+	// keep the origin of every suppression still in effect
+	disabledRules := merged.DisabledRules()
+	for _, suppression := range slices.Concat(base.suppressions, override.suppressions) {
+		if slices.Contains(disabledRules, suppression.Rule) {
+			merged.suppressions = append(merged.suppressions, suppression)
+		}
+	}
+
+	return &merged
+}
+
+// Suppressions returns all rules disabled by the directive
+func (d ScriptDirective) Suppressions() []Suppression {
+	return d.suppressions
+}
+
+// sequenceScriptDirective returns the directive applying to all
+// elements of the sequence, e.g. a trailing comment of a flow sequence
+func sequenceScriptDirective(node *ast.SequenceNode) *ScriptDirective {
+	if isSequenceHeadComment(node) {
+		return nil
+	}
+
+	return scriptDirectiveFromComment(node.GetComment())
+}
+
+// sequenceItemDirective returns the directive defined inside the head
+// comment of the sequence element at the given index
+func sequenceItemDirective(node *ast.SequenceNode, index int) *ScriptDirective {
+	// the parser attaches the head comment of the first
+	// element to the sequence node itself
+	if index == 0 && isSequenceHeadComment(node) {
+		return scriptDirectiveFromComment(node.GetComment())
+	}
+
+	if index < len(node.ValueHeadComments) {
+		return scriptDirectiveFromComment(node.ValueHeadComments[index])
+	}
+
+	return nil
+}
+
+func isSequenceHeadComment(node *ast.SequenceNode) bool {
+	comment := node.GetComment()
+	if comment == nil || node.IsFlowStyle || len(node.Values) == 0 {
+		return false
+	}
+
+	return comment.GetToken().Position.Line < node.Values[0].GetToken().Position.Line
+}
+
+// hasScriptDirective reports whether the node or, in case of a sequence,
+// one of its elements defines a scriptcheck directive
+func hasScriptDirective(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return hasScriptDirective(n.Value)
+	case *ast.SequenceNode:
+		return sequenceScriptDirective(n) != nil
+	default:
+		return findScriptCheckMarker(node.GetComment()) != nil
+	}
+}
+
+// documentDirectivesFromFile reads the file and document level directives
+// defined as head comment of each document. Directives of the first document
+// apply to the whole file, while directives of other documents get merged
+// into the file level directive.
+func documentDirectivesFromFile(file *ast.File) documentDirectiveMap {
+	directives := make(documentDirectiveMap)
+
+	var fileDirective *ScriptDirective
+	for index, doc := range file.Docs {
+		directive := fileDirectiveFromComment(documentHeadComment(doc))
+		if index == 0 {
+			fileDirective = directive
+		} else {
+			directive = mergeScriptDirectives(fileDirective, directive)
+		}
+
+		if directive != nil {
+			directives[doc] = directive
+		}
+	}
+
+	return directives
+}
+
+// documentHeadComment returns the comment in front of the
+// first node of the document body
+func documentHeadComment(doc *ast.DocumentNode) *ast.CommentGroupNode {
+	switch body := doc.Body.(type) {
+	case *ast.MappingNode:
+		if body.GetComment() != nil {
+			return body.GetComment()
+		} else if len(body.Values) > 0 {
+			return body.Values[0].GetComment()
+		}
+	case *ast.MappingValueNode:
+		return body.GetComment()
+	}
+
+	return nil
+}
+
+func fileDirectiveFromComment(comment *ast.CommentGroupNode) *ScriptDirective {
+	if directiveComment := findDirectiveComment(comment, scriptCheckFilePrefix); directiveComment != nil {
+		directive := fileDirectiveFromString(strings.TrimSpace(directiveComment.Token.Value))
+		directive = directive.withLine(directiveComment.Token.Position.Line)
+		return &directive
+	}
+
+	return nil
 }
 
 func scriptDirectiveFromComment(comment *ast.CommentGroupNode) *ScriptDirective {
-	if marker := findScriptCheckMarker(comment); marker != nil {
-		directive := scriptDirectiveFromString(*marker)
+	if directiveComment := findDirectiveComment(comment, scriptCheckPrefix); directiveComment != nil {
+		directive := scriptDirectiveFromString(strings.TrimSpace(directiveComment.Token.Value))
+		directive = directive.withLine(directiveComment.Token.Position.Line)
 		return &directive
 	}
 
@@ -54,16 +339,47 @@ func scriptDirectiveFromComment(comment *ast.CommentGroupNode) *ScriptDirective
 }
 
 func findScriptCheckMarker(comment *ast.CommentGroupNode) *string {
+	return findDirectiveMarker(comment, scriptCheckPrefix)
+}
+
+func findDirectiveMarker(comment *ast.CommentGroupNode, prefix string) *string {
+	if directiveComment := findDirectiveComment(comment, prefix); directiveComment != nil {
+		trimmed := strings.TrimSpace(directiveComment.Token.Value)
+		return &trimmed
+	}
+
+	return nil
+}
+
+func findDirectiveComment(comment *ast.CommentGroupNode, prefix string) *ast.CommentNode {
 	if comment == nil {
 		return nil
 	}
 
 	for _, comment := range comment.Comments {
 		trimmed := strings.TrimSpace(comment.Token.Value)
-		if strings.HasPrefix(trimmed, scriptCheckPrefix) {
-			return &trimmed
+		if isDirectiveMarker(trimmed, prefix) {
+			return comment
 		}
 	}
 
 	return nil
 }
+
+// IsScriptCheckComment reports whether the yaml comment,
+// including its leading #, is a scriptcheck directive
+func IsScriptCheckComment(comment string) bool {
+	value, isComment := strings.CutPrefix(strings.TrimSpace(comment), "#")
+	return isComment && isDirectiveMarker(strings.TrimSpace(value), scriptCheckPrefix)
+}
+
+// isDirectiveMarker reports whether the comment starts with the given
+// prefix followed by either nothing or whitespace
+func isDirectiveMarker(comment, prefix string) bool {
+	if !strings.HasPrefix(comment, prefix) {
+		return false
+	}
+
+	rest := comment[len(prefix):]
+	return len(rest) == 0 || unicode.IsSpace(rune(rest[0]))
+}
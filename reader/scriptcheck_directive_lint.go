@@ -0,0 +1,271 @@
+package reader
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml/ast"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// codes of problems found while linting scriptcheck directives
+const (
+	DirectiveUnknownKey      = "SD1001"
+	DirectiveMalformedRule   = "SD1002"
+	DirectiveUnknownShell    = "SD1003"
+	DirectiveUnknownSeverity = "SD1004"
+	DirectiveMissingValue    = "SD1005"
+	DirectiveWithoutScript   = "SD1006"
+	DirectiveMisplacedFile   = "SD1007"
+	DirectiveMissingReason   = "SD1008"
+
+	DirectiveUnusedSuppression = "SD2001"
+)
+
+// levels of problems found while linting scriptcheck directives
+const (
+	DirectiveLevelError   = "error"
+	DirectiveLevelWarning = "warning"
+)
+
+// shell dialects supported by shellcheck
+var supportedShells = []string{"sh", "bash", "dash", "ksh", "busybox"}
+
+var supportedSeverities = []string{"style", "info", "warning", "error"}
+
+var directiveKeys = []string{
+	directiveShell,
+	directiveDisable,
+	directiveEnable,
+	directiveIgnore,
+	directiveSkip,
+	directiveSeverity,
+	directiveSourcePath,
+	directiveReason,
+	directivePrelude,
+}
+
+// rules can optionally be prefixed by a dash in order to remove them
+var ruleCodeRegex = regexp.MustCompile(`^-?SC\d+$`)
+
+// DirectiveProblem describes an invalid scriptcheck directive
+type DirectiveProblem struct {
+	File    string
+	Path    string
+	Line    int
+	Column  int
+	Level   string
+	Code    string
+	Message string
+}
+
+// LintFile validates every scriptcheck directive defined in the given file.
+// When requireReason is set, every directive disabling rules needs to
+// provide a justification using the reason key.
+func (d ScriptDecoder) LintFile(file string, requireReason bool) ([]DirectiveProblem, error) {
+	astFile, _, err := d.readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	anchorWalker := &anchorWalker{
+		anchorNodeMap: make(map[string]ast.Node),
+		aliasValueMap: make(aliasValueMap),
+	}
+
+	for _, doc := range astFile.Docs {
+		if doc.Body != nil {
+			ast.Walk(anchorWalker, doc.Body)
+		}
+	}
+	if err := anchorWalker.resolutionError(file); err != nil {
+		return nil, err
+	}
+
+	linter := &directiveLinter{
+		file:          astFile,
+		parser:        d.parser,
+		aliasValueMap: anchorWalker.aliasValueMap,
+		requireReason: requireReason,
+		visited:       make(map[*ast.CommentNode]bool),
+		problems:      make([]DirectiveProblem, 0),
+	}
+
+	for _, doc := range astFile.Docs {
+		if doc.Body == nil {
+			continue
+		}
+
+		// file directives are only valid as head comment of a document
+		if headComment := documentHeadComment(doc); headComment != nil {
+			for _, comment := range headComment.Comments {
+				if isDirectiveMarker(strings.TrimSpace(comment.Token.Value), scriptCheckFilePrefix) {
+					linter.lintDirective(comment, scriptCheckFilePrefix, doc.Body.GetPath())
+				}
+			}
+		}
+
+		linter.document = doc
+		ast.Walk(linter, doc.Body)
+	}
+
+	return linter.problems, nil
+}
+
+type directiveLinter struct {
+	file     *ast.File
+	document *ast.DocumentNode
+
+	parser        scriptParser
+	aliasValueMap aliasValueMap
+	requireReason bool
+
+	// comments already linted as the parser might attach
+	// the same comment group to multiple nodes
+	visited  map[*ast.CommentNode]bool
+	problems []DirectiveProblem
+}
+
+func (l *directiveLinter) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case nil, *ast.CommentGroupNode:
+		return l
+	case *ast.MappingValueNode:
+		l.lintComment(n.GetComment(), n.Value)
+	case *ast.SequenceNode:
+		for i, element := range n.Values {
+			if i == 0 && isSequenceHeadComment(n) {
+				l.lintComment(n.GetComment(), element)
+			} else if i < len(n.ValueHeadComments) {
+				l.lintComment(n.ValueHeadComments[i], element)
+			}
+		}
+
+		if !isSequenceHeadComment(n) {
+			l.lintComment(n.GetComment(), n)
+		}
+	default:
+		l.lintComment(node.GetComment(), node)
+	}
+
+	return l
+}
+
+// lintComment validates all directives of the comment group
+// which should apply to the given target node
+func (l *directiveLinter) lintComment(group *ast.CommentGroupNode, target ast.Node) {
+	if group == nil {
+		return
+	}
+
+	for _, comment := range group.Comments {
+		if l.visited[comment] {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(comment.Token.Value)
+		if isDirectiveMarker(trimmed, scriptCheckFilePrefix) {
+			l.addProblem(
+				comment,
+				target.GetPath(),
+				DirectiveMisplacedFile,
+				"file directives are only supported at the top of a file or document",
+			)
+		} else if isDirectiveMarker(trimmed, scriptCheckPrefix) {
+			l.lintDirective(comment, scriptCheckPrefix, target.GetPath())
+
+			if scripts := l.parser(l.document, target, l.aliasValueMap); len(scripts) == 0 {
+				l.addProblem(
+					comment,
+					target.GetPath(),
+					DirectiveWithoutScript,
+					"directive is attached to a node without any script",
+				)
+			}
+		}
+	}
+}
+
+func (l *directiveLinter) lintDirective(comment *ast.CommentNode, prefix string, path string) {
+	l.visited[comment] = true
+
+	data := strings.TrimPrefix(strings.TrimSpace(comment.Token.Value), prefix)
+	directive := newScriptDirective(data)
+
+	for _, key := range slices.Sorted(maps.Keys(directive.values)) {
+		if !slices.Contains(directiveKeys, key) {
+			l.addProblem(comment, path, DirectiveUnknownKey, fmt.Sprintf("unknown directive key %q", key))
+			continue
+		}
+
+		for _, value := range directive.values[key] {
+			l.lintValue(comment, path, key, value)
+		}
+	}
+
+	if l.requireReason && len(directive.Suppressions()) > 0 && directive.value(directiveReason) == "" {
+		l.addProblem(comment, path, DirectiveMissingReason, "disabled rules require a justification using reason=\"...\"")
+	}
+}
+
+func (l *directiveLinter) lintValue(comment *ast.CommentNode, path, key, value string) {
+	switch key {
+	case directiveIgnore, directiveSkip:
+		return
+	}
+
+	if value == "" {
+		l.addProblem(comment, path, DirectiveMissingValue, fmt.Sprintf("missing value for directive key %q", key))
+		return
+	}
+
+	switch key {
+	case directiveShell:
+		if !slices.Contains(supportedShells, value) {
+			l.addProblem(
+				comment,
+				path,
+				DirectiveUnknownShell,
+				fmt.Sprintf("unsupported shell %q, expected one of %s", value, strings.Join(supportedShells, ", ")),
+			)
+		}
+	case directiveSeverity:
+		if !slices.Contains(supportedSeverities, value) {
+			l.addProblem(
+				comment,
+				path,
+				DirectiveUnknownSeverity,
+				fmt.Sprintf("unknown severity %q, expected one of %s", value, strings.Join(supportedSeverities, ", ")),
+			)
+		}
+	case directiveDisable:
+		for _, rule := range strings.Split(value, ",") {
+			if rule != "all" && !ruleCodeRegex.MatchString(strings.TrimSpace(rule)) {
+				l.addProblem(
+					comment,
+					path,
+					DirectiveMalformedRule,
+					fmt.Sprintf("malformed rule %q, expected a shellcheck code like SC2086", rule),
+				)
+			}
+		}
+	}
+}
+
+func (l *directiveLinter) addProblem(comment *ast.CommentNode, path, code, message string) {
+	level := DirectiveLevelError
+	if code == DirectiveWithoutScript || code == DirectiveMisplacedFile {
+		level = DirectiveLevelWarning
+	}
+
+	l.problems = append(l.problems, DirectiveProblem{
+		File:    l.file.Name,
+		Path:    path,
+		Line:    comment.Token.Position.Line,
+		Column:  comment.Token.Position.Column,
+		Level:   level,
+		Code:    code,
+		Message: message,
+	})
+}
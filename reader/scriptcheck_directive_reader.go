@@ -3,99 +3,145 @@ package reader
 import (
 	"fmt"
 	"github.com/goccy/go-yaml/ast"
+	"strings"
 )
 
-func newScriptCheckDirectiveDecoder(decoder ScriptDecoder) ScriptDecoder {
-	return ScriptDecoder{
-		ScriptReader: &scriptcheckDirectiveReader{
-			parser:                decoder.parser,
-			defaultShell:          decoder.defaultShell,
-			experirementalFolding: decoder.experimentalFolding,
-		},
-		defaultShell:        decoder.defaultShell,
-		debug:               decoder.debug,
-		parser:              decoder.parser,
-		experimentalFolding: decoder.experimentalFolding,
-	}
-}
-
-//goland:noinspection SpellCheckingInspection
-type scriptcheckDirectiveReader struct {
-	ScriptReader
-
-	experirementalFolding bool
-	defaultShell          string
-	parser                scriptParser
-}
-
+// scriptCheckDirectiveVisitor collects anchors as well as all nodes marked
+// by a scriptcheck directive within a single traversal of the file. Scripts
+// of the marked nodes get read afterward, as aliases inside a marked node
+// can only be resolved once the whole node got traversed.
 type scriptCheckDirectiveVisitor struct {
 	ast.Visitor
-	file *ast.File
+	anchorWalker
 
 	// currently looped document
 	document *ast.DocumentNode
 
-	Scripts             []ScriptBlock
-	experimentalFolding bool
+	candidates []directiveCandidate
 
-	reader        *scriptcheckDirectiveReader
-	aliasValueMap aliasValueMap
+	// sequences already read as value of a marked mapping value
+	coveredSequences map[*ast.SequenceNode]bool
 }
 
-func (reader *scriptcheckDirectiveReader) readScriptsForAst(
-	file *ast.File,
-	aliasValueMap aliasValueMap,
-) ([]ScriptBlock, error) {
-	directiveWalker := &scriptCheckDirectiveVisitor{
-		file:          file,
-		reader:        reader,
-		aliasValueMap: aliasValueMap,
+// directiveCandidate describes a node marked by a scriptcheck directive
+type directiveCandidate struct {
+	document  *ast.DocumentNode
+	blockName string
+	node      ast.Node
+	directive *ScriptDirective
+}
+
+func newScriptCheckDirectiveVisitor() *scriptCheckDirectiveVisitor {
+	return &scriptCheckDirectiveVisitor{
+		anchorWalker: anchorWalker{
+			anchorNodeMap: make(map[string]ast.Node),
+			aliasValueMap: make(aliasValueMap),
+		},
+		candidates:       make([]directiveCandidate, 0),
+		coveredSequences: make(map[*ast.SequenceNode]bool),
 	}
+}
 
+// walkFile traverses all documents of the file, a ResolutionError
+// is returned in case any alias references an unknown anchor
+func (v *scriptCheckDirectiveVisitor) walkFile(file *ast.File) error {
+	// otherwise the walker fails as body
+	// will be null for empty yaml files
 	for _, doc := range file.Docs {
 		if doc.Body != nil {
-			directiveWalker.document = doc
-			ast.Walk(directiveWalker, doc)
+			v.document = doc
+			ast.Walk(v, doc.Body)
 		}
 	}
 
-	return directiveWalker.Scripts, nil
+	return v.resolutionError(file.Name)
 }
 
 func (v *scriptCheckDirectiveVisitor) Visit(node ast.Node) ast.Visitor {
-	// currently only mapping value types are supported
-	if node.Type() != ast.MappingValueType {
-		return v
-	}
+	v.anchorWalker.Visit(node)
+
+	switch n := node.(type) {
+	case *ast.MappingValueNode:
+		directive := scriptDirectiveFromComment(n.GetComment())
+		if directive == nil && !hasScriptDirective(n.Value) {
+			return v
+		}
+
+		v.addCandidate("directive_"+n.Key.String(), n.Value, directive)
+
+		// sequence elements are already read as part of the value
+		value := n.Value
+		if anchor, isAnchor := value.(*ast.AnchorNode); isAnchor {
+			value = anchor.Value
+		}
+		if sequence, isSequence := value.(*ast.SequenceNode); isSequence {
+			v.coveredSequences[sequence] = true
+		}
+	case *ast.SequenceNode:
+		if v.coveredSequences[n] {
+			return v
+		}
 
-	if directive := scriptDirectiveFromComment(node.GetComment()); directive != nil {
-		mappingValueNode := node.(*ast.MappingValueNode)
-		name := mappingValueNode.Key.String()
-		nodeValue := mappingValueNode.Value
-
-		if scripts := v.reader.parser(v.document, nodeValue, v.aliasValueMap, v.experimentalFolding); len(scripts) > 0 {
-			blockName := "directive_" + name
-			for i, script := range scripts {
-				var elementName string
-				if i > 0 {
-					elementName = blockName + fmt.Sprintf("_%d", i)
-				} else {
-					elementName = blockName
-				}
-
-				scriptBlock := NewScriptBlock(
-					v.file.Name,
-					elementName,
-					v.reader.defaultShell,
-					script,
-					nodeValue,
-					directive,
-				)
-
-				v.Scripts = append(v.Scripts, scriptBlock)
+		for i, element := range n.Values {
+			if sequenceItemDirective(n, i) == nil && findScriptCheckMarker(element.GetComment()) == nil {
+				continue
 			}
+
+			blockName := "directive_" + blockNameFromPath(element.GetPath())
+			v.addCandidate(blockName, element, sequenceItemDirective(n, i))
 		}
 	}
 
 	return v
 }
+
+func (v *scriptCheckDirectiveVisitor) addCandidate(blockName string, node ast.Node, directive *ScriptDirective) {
+	v.candidates = append(v.candidates, directiveCandidate{
+		document:  v.document,
+		blockName: blockName,
+		node:      node,
+		directive: directive,
+	})
+}
+
+// readScripts reads the scripts of all nodes marked by a directive
+func (v *scriptCheckDirectiveVisitor) readScripts(
+	file *ast.File,
+	decoder ScriptDecoder,
+	documentDirectives documentDirectiveMap,
+) []ScriptBlock {
+	scripts := make([]ScriptBlock, 0)
+	for _, candidate := range v.candidates {
+		directive := mergeScriptDirectives(documentDirectives[candidate.document], candidate.directive)
+		nodeScripts := decoder.parser(candidate.document, candidate.node, v.aliasValueMap)
+		for i, script := range nodeScripts {
+			var elementName string
+			if i > 0 {
+				elementName = candidate.blockName + fmt.Sprintf("_%d", i)
+			} else {
+				elementName = candidate.blockName
+			}
+
+			scriptBlock := NewScriptBlock(
+				file.Name,
+				elementName,
+				decoder.defaultShell,
+				script,
+				candidate.node,
+				directive,
+			)
+
+			scripts = append(scripts, scriptBlock)
+		}
+	}
+
+	return scripts
+}
+
+// blockNameFromPath transforms the yaml path of a node
+// into a name usable as part of a file name
+func blockNameFromPath(path string) string {
+	name := strings.TrimPrefix(path, "$")
+	name = strings.NewReplacer(".", "_", "[", "_", "]", "", "'", "").Replace(name)
+	return strings.Trim(name, "_")
+}
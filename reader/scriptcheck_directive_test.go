@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDirectiveFromString(t *testing.T) {
+	cases := []struct {
+		directive string
+		shell     string
+		disabled  []string
+		enabled   []string
+		severity  string
+		ignored   bool
+	}{
+		{
+			directive: "scriptcheck shell=bash disable=SC2086",
+			shell:     "bash",
+			disabled:  []string{"SC2086"},
+		},
+		{
+			directive: "scriptcheck  disable=SC2086,SC2046   disable=SC2154",
+			disabled:  []string{"SC2086", "SC2046", "SC2154"},
+		},
+		{
+			directive: "scriptcheck enable=require-variable-braces severity=warning",
+			enabled:   []string{"require-variable-braces"},
+			severity:  "warning",
+		},
+		{
+			directive: "scriptcheck ignore",
+			ignored:   true,
+		},
+		{
+			directive: "scriptcheck skip shell=sh",
+			shell:     "sh",
+			ignored:   true,
+		},
+	}
+
+	for _, c := range cases {
+		directive := scriptDirectiveFromString(c.directive)
+		if directive.ShellDirective() != c.shell {
+			t.Errorf("%q: expected shell %q, got %q", c.directive, c.shell, directive.ShellDirective())
+		}
+		if !slices.Equal(directive.DisabledRules(), c.disabled) {
+			t.Errorf("%q: expected disabled rules %v, got %v", c.directive, c.disabled, directive.DisabledRules())
+		}
+		if !slices.Equal(directive.EnabledChecks(), c.enabled) {
+			t.Errorf("%q: expected enabled checks %v, got %v", c.directive, c.enabled, directive.EnabledChecks())
+		}
+		if directive.Severity() != c.severity {
+			t.Errorf("%q: expected severity %q, got %q", c.directive, c.severity, directive.Severity())
+		}
+		if directive.Ignored() != c.ignored {
+			t.Errorf("%q: expected ignored to be %t", c.directive, c.ignored)
+		}
+	}
+}
+
+func TestDirectiveQuotedValues(t *testing.T) {
+	directive := scriptDirectiveFromString(`scriptcheck source-path="ci/some dir" source-path='lib' reason="needs \"globbing\""`)
+
+	if paths := directive.SourcePaths(); !slices.Equal(paths, []string{"ci/some dir", "lib"}) {
+		t.Errorf("unexpected source paths %v", paths)
+	}
+
+	if reason := directive.value("reason"); reason != `needs "globbing"` {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func TestMergeDirectives(t *testing.T) {
+	base := scriptDirectiveFromString("scriptcheck shell=sh disable=SC2086")
+	override := scriptDirectiveFromString("scriptcheck shell=bash disable=SC2046")
+
+	merged := mergeScriptDirectives(&base, &override)
+	if merged.ShellDirective() != "bash" {
+		t.Errorf("expected shell of override, got %q", merged.ShellDirective())
+	}
+	if !slices.Equal(merged.DisabledRules(), []string{"SC2086", "SC2046"}) {
+		t.Errorf("expected combined disabled rules, got %v", merged.DisabledRules())
+	}
+	if !slices.Equal(base.DisabledRules(), []string{"SC2086"}) {
+		t.Errorf("merging should not modify the base directive")
+	}
+}
+
+func TestDirectiveMarker(t *testing.T) {
+	cases := map[string]bool{
+		"scriptcheck":               true,
+		"scriptcheck shell=sh":      true,
+		"scriptcheck-file shell=sh": false,
+		"scriptchecker":             false,
+	}
+
+	for comment, expected := range cases {
+		if isDirectiveMarker(comment, scriptCheckPrefix) != expected {
+			t.Errorf("%q: expected marker to be %t", comment, expected)
+		}
+	}
+}
+
+func TestFileDirective(t *testing.T) {
+	scripts, err := newGitlabDecoder(false, "").DecodeFile("../dir/file_directive.yml")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	expected := map[string]string{
+		"job_1_script": "# shellcheck shell=bash disable=SC2154,SC2046\n",
+		"job_2_script": "# shellcheck shell=sh disable=SC2046,SC2086\n",
+	}
+
+	for _, script := range scripts {
+		header := script.directive.asShellcheckDirective(script)
+		if header != expected[script.BlockName] {
+			t.Errorf("%s: unexpected shellcheck directive %q", script.BlockName, header)
+		}
+	}
+}
+
+func TestLintDirectives(t *testing.T) {
+	problems, err := newGitlabDecoder(false, "").LintFile("../dir/directive.yml", false)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	codes := make([]string, 0)
+	for _, problem := range problems {
+		codes = append(codes, problem.Code)
+	}
+
+	slices.Sort(codes)
+	if !slices.Equal(codes, []string{DirectiveUnknownShell, DirectiveWithoutScript}) {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
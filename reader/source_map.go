@@ -0,0 +1,535 @@
+package reader
+
+import (
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ScalarStyle describes how a script is written inside the yaml file
+type ScalarStyle int
+
+const (
+	PlainStyle ScalarStyle = iota
+	SingleQuotedStyle
+	DoubleQuotedStyle
+	LiteralStyle
+	FoldedStyle
+)
+
+// SourcePosition is a position inside the yaml file,
+// where lines and columns start at 1
+type SourcePosition struct {
+	Line   int
+	Column int
+}
+
+// SourceMap maps positions inside a script back to the yaml file
+type SourceMap struct {
+	Style ScalarStyle
+
+	// indentation of block scalars
+	Indent int
+
+	lines []sourceLine
+}
+
+// sourceLine maps a single script line to the yaml file
+type sourceLine struct {
+	// yaml position of every character of the script line, where
+	// the last position denotes the position following the line
+	positions []SourcePosition
+}
+
+// Position returns the yaml position of the given script position,
+// the column may point to the position following the script line
+func (m *SourceMap) Position(line, column int) (SourcePosition, bool) {
+	if m == nil || line < 1 || line > len(m.lines) {
+		return SourcePosition{}, false
+	}
+
+	sourceLine := m.lines[line-1]
+	if column < 1 || column > len(sourceLine.positions) {
+		return SourcePosition{}, false
+	}
+
+	return sourceLine.positions[column-1], true
+}
+
+// End returns the yaml position following the last character of the script
+func (m *SourceMap) End() (SourcePosition, bool) {
+	if m == nil || len(m.lines) == 0 {
+		return SourcePosition{}, false
+	}
+
+	positions := m.lines[len(m.lines)-1].positions
+	if len(positions) == 0 {
+		return SourcePosition{}, false
+	}
+
+	return positions[len(positions)-1], true
+}
+
+// readSourceScript reads the script of the node from the lines of the yaml
+// file and returns it together with its source map. Block scalars are read
+// according to the yaml spec, as the parser does not fold all of them
+// correctly. The given script is returned without source map, in case
+// the node can not be read exactly.
+func readSourceScript(lines []string, node ast.Node, script Script) (Script, *SourceMap) {
+	var text *mappedText
+	sourceMap := &SourceMap{}
+
+	switch n := node.(type) {
+	case *ast.LiteralNode:
+		text, sourceMap.Indent = readBlockScalar(lines, n)
+		sourceMap.Style = LiteralStyle
+		if n.Start.Type == token.FoldedType {
+			sourceMap.Style = FoldedStyle
+		}
+	case *ast.StringNode:
+		text, sourceMap.Style = readFlowScalar(lines, n)
+
+		// flow scalars need to match the value of the parser
+		if text != nil && string(text.runes) != n.Value {
+			text = nil
+		}
+	}
+
+	if text == nil {
+		return script, nil
+	}
+
+	text = text.delete(inputReferenceRanges(string(text.runes)))
+	for _, line := range text.lines() {
+		sourceMap.lines = append(sourceMap.lines, sourceLine{line})
+	}
+
+	return Script(text.runes), sourceMap
+}
+
+// mappedText is text read from the yaml file, where every
+// character is mapped to the position it was read from
+type mappedText struct {
+	runes     []rune
+	positions []SourcePosition
+
+	// position following the text
+	end SourcePosition
+}
+
+func (t *mappedText) add(r rune, line, column int) {
+	t.runes = append(t.runes, r)
+	t.positions = append(t.positions, SourcePosition{line, column})
+}
+
+// delete returns the text without the given byte ranges
+func (t *mappedText) delete(ranges [][2]int) *mappedText {
+	if len(ranges) == 0 {
+		return t
+	}
+
+	deleted := &mappedText{end: t.end}
+	offset := 0
+	for i, r := range t.runes {
+		isDeleted := false
+		for _, deletedRange := range ranges {
+			isDeleted = isDeleted || (offset >= deletedRange[0] && offset < deletedRange[1])
+		}
+
+		if !isDeleted {
+			deleted.runes = append(deleted.runes, r)
+			deleted.positions = append(deleted.positions, t.positions[i])
+		}
+		offset += utf8.RuneLen(r)
+	}
+
+	return deleted
+}
+
+// lines returns the positions of every line of the text, where the
+// last position of a line is the one of its line break
+func (t *mappedText) lines() [][]SourcePosition {
+	lines := make([][]SourcePosition, 0)
+	line := make([]SourcePosition, 0)
+	for i, r := range t.runes {
+		line = append(line, t.positions[i])
+		if r == '\n' {
+			lines = append(lines, line)
+			line = make([]SourcePosition, 0)
+		}
+	}
+
+	return append(lines, append(line, t.end))
+}
+
+// readBlockScalar reads the literal or folded block scalar of the node
+// and returns the read text as well as the indentation of its content
+func readBlockScalar(lines []string, node *ast.LiteralNode) (*mappedText, int) {
+	headerLine := node.Start.Position.Line
+	if headerLine < 1 || headerLine > len(lines) {
+		return nil, 0
+	}
+
+	header := node.Start.Value
+	chomping := byte(0)
+	indicator := 0
+	for i := 1; i < len(header); i++ {
+		switch {
+		case header[i] == '-' || header[i] == '+':
+			chomping = header[i]
+		case header[i] >= '1' && header[i] <= '9':
+			indicator = int(header[i] - '0')
+		}
+	}
+
+	parent := parentIndent(lines[headerLine-1], node.Start.Position.Column)
+
+	// content lines, where empty lines are nil
+	content := make([][]rune, 0)
+	indent := -1
+	if indicator > 0 {
+		indent = max(parent, 0) + indicator
+	}
+	for _, line := range lines[headerLine:] {
+		runes := []rune(line)
+		spaces := leadingSpaces(runes)
+		if spaces == len(runes) && (indent < 0 || spaces <= indent) {
+			content = append(content, nil)
+			continue
+		}
+
+		if indent < 0 {
+			if spaces <= parent {
+				break
+			}
+			indent = spaces
+		}
+
+		if spaces < indent {
+			break
+		}
+		content = append(content, runes[indent:])
+	}
+
+	// trailing empty lines are subject to chomping
+	lastContent := len(content) - 1
+	for lastContent >= 0 && content[lastContent] == nil {
+		lastContent--
+	}
+
+	if indent < 0 {
+		indent = max(parent+1, 0)
+	}
+
+	text := new(mappedText)
+	lineEnd := func(i int) SourcePosition {
+		return SourcePosition{headerLine + 1 + i, min(indent, utf8.RuneCountInString(lines[headerLine+i])) + len(content[i]) + 1}
+	}
+	addBreak := func(i int) {
+		end := lineEnd(i)
+		text.add('\n', end.Line, end.Column)
+	}
+
+	isFolded := node.Start.Type == token.FoldedType
+	previous := -1
+	for i := 0; i <= lastContent; i++ {
+		if content[i] == nil {
+			continue
+		}
+
+		emptyLines := i - previous - 1
+		switch {
+		case previous < 0:
+			// leading empty lines are kept
+			for j := 0; j < emptyLines; j++ {
+				addBreak(j)
+			}
+		case isFolded && !isSpacedLine(content[previous]) && !isSpacedLine(content[i]):
+			if emptyLines == 0 {
+				end := lineEnd(previous)
+				text.add(' ', end.Line, end.Column)
+			}
+			for j := previous + 1; j < i; j++ {
+				addBreak(j)
+			}
+		default:
+			for j := previous; j < i; j++ {
+				addBreak(j)
+			}
+		}
+
+		for column, r := range content[i] {
+			text.add(r, headerLine+1+i, indent+column+1)
+		}
+		previous = i
+	}
+
+	switch {
+	case previous < 0:
+		text.end = SourcePosition{headerLine, utf8.RuneCountInString(lines[headerLine-1]) + 1}
+		if chomping == '+' {
+			for j := range content {
+				addBreak(j)
+			}
+		}
+	case chomping == '-':
+		text.end = lineEnd(previous)
+	case chomping == '+':
+		for j := previous; j < len(content); j++ {
+			addBreak(j)
+		}
+		text.end = text.positions[len(text.positions)-1]
+	default:
+		addBreak(previous)
+		text.end = text.positions[len(text.positions)-1]
+	}
+
+	return text, indent
+}
+
+// parentIndent returns the indentation of the node containing the block
+// scalar starting at the given column of the header line, which is the
+// indentation of the mapping key or the one of the sequence entry
+func parentIndent(line string, column int) int {
+	runes := []rune(line)
+	prefix := strings.Fields(string(runes[:min(max(column-1, 0), len(runes))]))
+
+	// skip node properties like anchors and tags
+	for len(prefix) > 0 && strings.ContainsAny(prefix[len(prefix)-1][:1], "&!") {
+		prefix = prefix[:len(prefix)-1]
+	}
+
+	if len(prefix) == 0 {
+		return -1
+	}
+
+	indent := leadingSpaces(runes)
+	if prefix[len(prefix)-1] == "-" {
+		// indentation of the last sequence entry
+		return indent + 2*(len(prefix)-1)
+	}
+
+	// indentation of the key following compact sequence entries
+	for _, field := range prefix {
+		if field != "-" {
+			break
+		}
+		indent += 2
+	}
+
+	return indent
+}
+
+func leadingSpaces(runes []rune) int {
+	spaces := 0
+	for spaces < len(runes) && runes[spaces] == ' ' {
+		spaces++
+	}
+
+	return spaces
+}
+
+// isSpacedLine reports whether the content line of a folded
+// block is more indented, which prevents it from being folded
+func isSpacedLine(line []rune) bool {
+	return len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+}
+
+// readFlowScalar reads the plain or quoted scalar of the node, where
+// line breaks get folded and escapes of double-quoted scalars resolved
+func readFlowScalar(lines []string, node *ast.StringNode) (*mappedText, ScalarStyle) {
+	nodeToken := node.GetToken()
+	style := PlainStyle
+	switch nodeToken.Type {
+	case token.SingleQuoteType:
+		style = SingleQuotedStyle
+	case token.DoubleQuoteType:
+		style = DoubleQuotedStyle
+	case token.StringType:
+	default:
+		return nil, style
+	}
+
+	line := nodeToken.Position.Line
+	if line < 1 || line > len(lines) || node.Value == "" {
+		return nil, style
+	}
+
+	// the reported column may be off for lines containing tabs,
+	// thus the scalar is searched starting from the column
+	runes := []rune(lines[line-1])
+	first := []rune(node.Value)[0]
+	switch style {
+	case SingleQuotedStyle:
+		first = '\''
+	case DoubleQuotedStyle:
+		first = '"'
+	}
+
+	index := max(nodeToken.Position.Column-2, 0)
+	for index < len(runes) && runes[index] != first {
+		index++
+	}
+	if index >= len(runes) {
+		return nil, style
+	}
+
+	if style != PlainStyle {
+		index++
+	}
+
+	scanner := flowScanner{lines: lines, line: line, runes: runes, index: index, text: new(mappedText)}
+	length := utf8.RuneCountInString(node.Value)
+	for {
+		if style == PlainStyle && len(scanner.text.runes) >= length {
+			scanner.text.end = scanner.position()
+			return scanner.text, style
+		}
+
+		if scanner.index >= len(runes) || scanner.isLineEnd() {
+			if !scanner.fold() {
+				return nil, style
+			}
+			runes = scanner.runes
+			continue
+		}
+
+		r := runes[scanner.index]
+		switch {
+		case style == SingleQuotedStyle && r == '\'':
+			if scanner.index+1 < len(runes) && runes[scanner.index+1] == '\'' {
+				scanner.add('\'')
+				scanner.index += 2
+				continue
+			}
+			scanner.text.end = scanner.position()
+			return scanner.text, style
+		case style == DoubleQuotedStyle && r == '"':
+			scanner.text.end = scanner.position()
+			return scanner.text, style
+		case style == DoubleQuotedStyle && r == '\\':
+			if scanner.index+1 >= len(runes) {
+				// escaped line breaks are removed including
+				// the indentation of the following line
+				if !scanner.nextLine() {
+					return nil, style
+				}
+				runes = scanner.runes
+				scanner.addEmptyLines()
+				runes = scanner.runes
+				continue
+			}
+
+			escaped, size := unescape(runes[scanner.index+1:])
+			if size == 0 {
+				return nil, style
+			}
+			scanner.add(escaped)
+			scanner.index += size + 1
+		default:
+			scanner.add(r)
+			scanner.index++
+		}
+	}
+}
+
+// flowScanner scans a flow scalar line by line
+type flowScanner struct {
+	lines []string
+	line  int
+	runes []rune
+	index int
+
+	text *mappedText
+}
+
+func (s *flowScanner) position() SourcePosition {
+	return SourcePosition{s.line, s.index + 1}
+}
+
+func (s *flowScanner) add(r rune) {
+	s.text.add(r, s.line, s.index+1)
+}
+
+// isLineEnd reports whether only whitespace follows on the current line
+func (s *flowScanner) isLineEnd() bool {
+	return strings.TrimLeft(string(s.runes[s.index:]), " \t") == ""
+}
+
+// nextLine continues at the first non-whitespace character of the next line
+func (s *flowScanner) nextLine() bool {
+	if s.line >= len(s.lines) {
+		return false
+	}
+
+	s.line++
+	s.runes = []rune(s.lines[s.line-1])
+	s.index = 0
+	for s.index < len(s.runes) && (s.runes[s.index] == ' ' || s.runes[s.index] == '\t') {
+		s.index++
+	}
+
+	return true
+}
+
+// addEmptyLines adds a line break for every following empty line
+func (s *flowScanner) addEmptyLines() {
+	for s.index >= len(s.runes) && s.line < len(s.lines) {
+		s.add('\n')
+		s.nextLine()
+	}
+}
+
+// fold folds the line break at the end of the current line, which is
+// a space in case no empty lines follow, otherwise a line break per
+// empty line
+func (s *flowScanner) fold() bool {
+	for s.index < len(s.runes) && (s.runes[s.index] == ' ' || s.runes[s.index] == '\t') {
+		s.index++
+	}
+
+	// trailing whitespace is not part of the scalar
+	end := len(strings.TrimRight(string(s.runes), " \t"))
+	end = utf8.RuneCountInString(string(s.runes)[:end])
+	foldLine, foldColumn := s.line, end+1
+
+	if !s.nextLine() {
+		return false
+	}
+
+	if s.index < len(s.runes) {
+		s.text.add(' ', foldLine, foldColumn)
+		return true
+	}
+
+	s.addEmptyLines()
+	return s.index < len(s.runes)
+}
+
+// unescape resolves the escape sequence of a double-quoted scalar
+// following the backslash and returns the number of read characters
+func unescape(runes []rune) (rune, int) {
+	simple := map[rune]rune{
+		'0': 0, 'a': '\a', 'b': '\b', 't': '\t', '\t': '\t', 'n': '\n', 'v': '\v', 'f': '\f',
+		'r': '\r', 'e': '\x1b', ' ': ' ', '"': '"', '/': '/', '\\': '\\', 'N': '\u0085',
+		'_': '\u00a0', 'L': '\u2028', 'P': '\u2029',
+	}
+
+	if r, exists := simple[runes[0]]; exists {
+		return r, 1
+	}
+
+	digits := map[rune]int{'x': 2, 'u': 4, 'U': 8}[runes[0]]
+	if digits == 0 || len(runes) <= digits {
+		return 0, 0
+	}
+
+	code, err := strconv.ParseUint(string(runes[1:digits+1]), 16, 32)
+	if err != nil {
+		return 0, 0
+	}
+
+	return rune(code), digits + 1
+}
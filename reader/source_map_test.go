@@ -0,0 +1,65 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	yaml := strings.Join([]string{
+		"job:",
+		"  script:",
+		"    - >",
+		"      echo one",
+		"      $TWO",
+		"",
+		"      three",
+		"    - |2-",
+		"        indented",
+		"      $X",
+		"    - plain",
+		"      $MULTI",
+		"    - \"\\\"dq\\\" \\t",
+		"      $DQ\"",
+		"    - 'it''s $SQ'",
+		"    - echo $[[ inputs.name ]] $INPUT",
+		"    - \"echo \\_\\L\\P $ESC\"",
+		"",
+	}, "\n")
+
+	tests := []struct {
+		script string
+		// script position of the variable and its expected yaml position
+		line, column int
+		expected     SourcePosition
+	}{
+		{"echo one $TWO\nthree\n", 1, 10, SourcePosition{5, 7}},
+		{"  indented\n$X", 2, 1, SourcePosition{10, 7}},
+		{"plain $MULTI", 1, 7, SourcePosition{12, 7}},
+		{"\"dq\" \t $DQ", 1, 8, SourcePosition{14, 7}},
+		{"it's $SQ", 1, 6, SourcePosition{15, 14}},
+		{"echo inputs.name $INPUT", 1, 18, SourcePosition{16, 31}},
+		{"echo \u00a0\u2028\u2029 $ESC", 1, 10, SourcePosition{17, 20}},
+	}
+
+	scripts, err := newGitlabDecoder(false, "").DecodeReader("test.yml", strings.NewReader(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if len(scripts) != len(tests) {
+		t.Fatalf("expected %d scripts, got %d", len(tests), len(scripts))
+	}
+
+	for i, test := range tests {
+		if string(scripts[i].Script) != test.script {
+			t.Errorf("expected script %q, got %q", test.script, scripts[i].Script)
+			continue
+		}
+
+		position, exists := scripts[i].SourceMap().Position(test.line, test.column)
+		if !exists || position != test.expected {
+			t.Errorf("expected %q to map to %v, got %v", test.script, test.expected, position)
+		}
+	}
+}
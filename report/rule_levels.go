@@ -0,0 +1,46 @@
+package report
+
+// RuleLevels maps reasons of reports to the level they are reported
+// with, independent of the level reported by shellcheck
+type RuleLevels map[string]string
+
+// Profiles lists the built-in profiles of rule levels
+var Profiles = map[string]RuleLevels{
+	// treat findings likely causing bugs or security issues as errors
+	"strict": {
+		"SC2046": "error",
+		"SC2048": "error",
+		"SC2068": "error",
+		"SC2086": "error",
+		"SC2115": "error",
+		"SC2164": "error",
+		"SC2006": "warning",
+		"SC2155": "warning",
+	},
+	// variables of pipelines are commonly provided by the environment
+	// or used by later scripts of the job
+	"recommended": {
+		"SC2034": "info",
+		"SC2154": "info",
+	},
+	// only report findings likely breaking existing scripts
+	"legacy": {
+		"SC2006": "style",
+		"SC2034": "style",
+		"SC2046": "info",
+		"SC2086": "info",
+		"SC2154": "style",
+		"SC2155": "style",
+		"SC2164": "info",
+	},
+}
+
+// levelOf returns the remapped level of the reason, or the given level
+// in case the reason is not remapped
+func (l RuleLevels) levelOf(reason, level string) string {
+	if remapped, exists := l[reason]; exists {
+		return remapped
+	}
+
+	return level
+}
@@ -1,12 +1,18 @@
 package report
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"scriptcheck/reader"
+	"slices"
 	"strconv"
+	"strings"
 )
 
+// Levels lists the shellcheck levels ordered by ascending severity
+var Levels = []string{"style", "info", "warning", "error"}
+
 type ScriptCheckReport struct {
 	// name of the yaml file
 	File string `json:"file"`
@@ -14,20 +20,18 @@ type ScriptCheckReport struct {
 	// path inside the yaml file
 	Path string `json:"path"`
 
-	// shellcheck level of the report
+	// shellcheck level of the report, remapped by the rule levels
 	Level string `json:"level"`
 
-	// line of the found violation inside yaml file
-	Line int `json:"line"`
-
-	// column where the violation was found
-	// currently ignored for json format as we
-	// do not support 100% correct columns in
-	// the checked yaml file
-	Column    int `json:"-"`
-	EndColumn int `json:"-"`
+	// range of the found violation inside the yaml file, where
+	// the end column points to the position following the range
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
 
-	// shellcheck reason (code prefixed by SC)
+	// shellcheck reason (code prefixed by SC) or
+	// the reason provided by another checker
 	Reason string `json:"reason"`
 
 	// shellcheck message
@@ -43,54 +47,149 @@ type ScriptCheckReport struct {
 func NewScriptCheckReport(
 	reportBytes []byte,
 	scriptMap map[string]reader.ScriptBlock,
+	ruleLevels RuleLevels,
 ) ([]ScriptCheckReport, error) {
+	if shellCheckReport, err := ParseShellcheckReports(reportBytes); err != nil {
+		return nil, err
+	} else {
+		return newScriptCheckReport(shellCheckReport, scriptMap, ruleLevels), nil
+	}
+}
+
+// ParseShellcheckReports parses the json output of shellcheck
+func ParseShellcheckReports(reportBytes []byte) ([]ShellcheckReport, error) {
 	if shellCheckReport, err := shellCheckReportFromString(reportBytes); err != nil {
 		return nil, fmt.Errorf("unable to parse shellcheck report: %w", err)
 	} else {
-		return newScriptCheckReport(shellCheckReport, scriptMap), nil
+		return shellCheckReport, nil
 	}
 }
 
-func newScriptCheckReport(reports []ShellcheckReport, scriptMap map[string]reader.ScriptBlock) []ScriptCheckReport {
+// MapShellcheckReports maps the shellcheck reports of the checked
+// files to the positions of their scripts inside the yaml files,
+// levels of the reports get remapped by the rule levels
+func MapShellcheckReports(
+	reports []ShellcheckReport,
+	scriptMap map[string]reader.ScriptBlock,
+	ruleLevels RuleLevels,
+) []ScriptCheckReport {
+	return newScriptCheckReport(reports, scriptMap, ruleLevels)
+}
+
+func newScriptCheckReport(
+	reports []ShellcheckReport,
+	scriptMap map[string]reader.ScriptBlock,
+	ruleLevels RuleLevels,
+) []ScriptCheckReport {
 	scriptCheckReports := make([]ScriptCheckReport, 0)
 	for _, report := range reports {
 		scriptBlock := scriptMap[report.File]
+		reason := cmp.Or(report.Reason, "SC"+strconv.Itoa(report.Code))
+		level := ruleLevels.levelOf(reason, report.Level)
+
+		// skip reports below the minimum severity of the script
+		if IsBelowSeverity(level, scriptBlock.Severity()) {
+			continue
+		}
 
-		var offset = 0
+		// lines of the shellcheck directive and the prelude
+		// need to be subtracted from the reported line
+		offset := scriptBlock.HeaderLines()
 
-		// when the scriptblock defined a shell directive we need
-		// to subtract one line from the reported line
-		if scriptBlock.HasShell() || scriptBlock.HasShellDirective() {
-			offset = 1
+		// skip reports for the prelude, which is only
+		// defined to provide context for the script
+		if report.Line <= offset {
+			continue
 		}
 
-		reason := "SC" + strconv.Itoa(report.Code)
-		// the report starts at 1 so we need to subtract one in order
-		// to get the correct line inside the yaml file
-		reportLineBase := report.Line - 1
 		scriptReport := ScriptCheckReport{
 			File:    scriptBlock.FileName,
 			Report:  report,
-			Level:   report.Level,
+			Level:   level,
 			Message: report.Message,
 
-			Line:      scriptBlock.StartPos + reportLineBase - offset,
-			Column:    report.Column,
-			EndColumn: report.EndColumn,
-
 			Path:   scriptBlock.Path,
 			Reason: reason,
 			Script: scriptBlock,
 		}
+
+		start, startExists := scriptBlock.SourcePosition(report.Line, report.Column)
+		end, endExists := scriptBlock.SourcePosition(max(report.EndLine, report.Line), report.EndColumn)
+		if startExists && endExists {
+			scriptReport.Line, scriptReport.Column = start.Line, start.Column
+			scriptReport.EndLine, scriptReport.EndColumn = end.Line, end.Column
+		} else {
+			// for scripts which can not be mapped exactly the lines
+			// are assumed to match, while columns refer to the script
+			scriptReport.Line = scriptBlock.StartPos + report.Line - 1 - offset
+			scriptReport.EndLine = scriptBlock.StartPos + max(report.EndLine, report.Line) - 1 - offset
+			scriptReport.Column, scriptReport.EndColumn = report.Column, report.EndColumn
+		}
 		scriptCheckReports = append(scriptCheckReports, scriptReport)
 	}
 
 	return scriptCheckReports
 }
 
+// NewDirectiveReports creates reports for problems found
+// while linting scriptcheck directives
+func NewDirectiveReports(problems []reader.DirectiveProblem) []ScriptCheckReport {
+	scriptCheckReports := make([]ScriptCheckReport, 0, len(problems))
+	for _, problem := range problems {
+		scriptCheckReports = append(scriptCheckReports, ScriptCheckReport{
+			File:      problem.File,
+			Path:      problem.Path,
+			Level:     problem.Level,
+			Line:      problem.Line,
+			Column:    problem.Column,
+			EndLine:   problem.Line,
+			EndColumn: problem.Column,
+			Reason:    problem.Code,
+			Message:   problem.Message,
+		})
+	}
+
+	return scriptCheckReports
+}
+
+// SortReports sorts the reports by file, position and reason
+func SortReports(reports []ScriptCheckReport) {
+	slices.SortStableFunc(reports, func(a, b ScriptCheckReport) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.Reason, b.Reason),
+			cmp.Compare(a.Path, b.Path),
+		)
+	})
+}
+
+// IsBelowSeverity reports whether the given shellcheck level is lower
+// than the given severity. Empty or unknown severities include every level.
+func IsBelowSeverity(level, severity string) bool {
+	severityRank := slices.Index(Levels, severity)
+	if severityRank < 0 {
+		return false
+	}
+
+	return slices.Index(Levels, level) < severityRank
+}
+
+// shellCheckReportFromString parses the json as well
+// as the json1 format, which wraps the reports
 func shellCheckReportFromString(bytes []byte) ([]ShellcheckReport, error) {
 	var report []ShellcheckReport
-	err := json.Unmarshal(bytes, &report)
+	var err error
+	if trimmed := strings.TrimSpace(string(bytes)); strings.HasPrefix(trimmed, "{") {
+		var wrapped struct {
+			Comments []ShellcheckReport `json:"comments"`
+		}
+		err = json.Unmarshal(bytes, &wrapped)
+		report = wrapped.Comments
+	} else {
+		err = json.Unmarshal(bytes, &report)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse shellcheck output: %w", err)
@@ -9,4 +9,27 @@ type ShellcheckReport struct {
 	Level     string `json:"level"`
 	Code      int    `json:"code"`
 	Message   string `json:"message"`
+
+	// reason of reports created by checkers other than
+	// shellcheck, which do not provide a shellcheck code
+	Reason string `json:"reason,omitempty"`
+
+	// automatic fix provided by shellcheck
+	Fix *ShellcheckFix `json:"fix,omitempty"`
+}
+
+type ShellcheckFix struct {
+	Replacements []ShellcheckReplacement `json:"replacements"`
+}
+
+// ShellcheckReplacement replaces the range between the start and
+// the exclusive end position of the checked script by the replacement
+type ShellcheckReplacement struct {
+	Line           int    `json:"line"`
+	EndLine        int    `json:"endLine"`
+	Column         int    `json:"column"`
+	EndColumn      int    `json:"endColumn"`
+	InsertionPoint string `json:"insertionPoint"`
+	Precedence     int    `json:"precedence"`
+	Replacement    string `json:"replacement"`
 }
@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"scriptcheck/color"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+)
+
+// DefaultBaselineFile is the baseline used by --update-baseline
+// in case no baseline file is given
+const DefaultBaselineFile = "scriptcheck-baseline.json"
+
+const baselineVersion = 1
+
+// baselineFile contains all findings accepted when the baseline was created
+type baselineFile struct {
+	Version  int               `json:"version"`
+	Findings []baselineFinding `json:"findings"`
+}
+
+// baselineFinding identifies a finding independent of its position,
+// thus findings remain matched when lines get added or removed
+type baselineFinding struct {
+	Fingerprint string `json:"fingerprint"`
+	File        string `json:"file"`
+	Job         string `json:"job"`
+	Section     string `json:"section"`
+	Reason      string `json:"reason"`
+	Snippet     string `json:"snippet"`
+}
+
+// baseline filters findings already contained in the baseline file, every
+// finding of the baseline matches at most one finding of the current run
+type baseline struct {
+	fileName string
+	update   bool
+	logger   *log.Logger
+	// whether only changed scripts get checked, thus missing
+	// findings of the baseline are not necessarily fixed
+	changesOnly bool
+
+	findings []baselineFinding
+	// number of unmatched findings per fingerprint
+	remaining map[string]int
+
+	matchedCount int
+	// all findings of the current run written on update
+	current []baselineFinding
+}
+
+// newBaseline reads the configured baseline file, nil is
+// returned in case no baseline is used
+func newBaseline(options *Options) (*baseline, error) {
+	if options.Baseline == "" && !options.UpdateBaseline {
+		return nil, nil
+	}
+
+	b := &baseline{
+		fileName:    cmp.Or(options.Baseline, DefaultBaselineFile),
+		update:      options.UpdateBaseline,
+		logger:      options.logger(),
+		changesOnly: options.ChangedSince != "",
+		remaining:   make(map[string]int),
+		current:     make([]baselineFinding, 0),
+	}
+
+	if b.update {
+		return b, nil
+	}
+
+	content, err := os.ReadFile(b.fileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("baseline %s does not exist, create it using --update-baseline", b.fileName)
+		}
+		return nil, fmt.Errorf("unable to read baseline: %w", err)
+	}
+
+	var file baselineFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse baseline %s: %w", b.fileName, err)
+	}
+
+	b.findings = file.Findings
+	for _, finding := range b.findings {
+		b.remaining[finding.Fingerprint]++
+	}
+
+	return b, nil
+}
+
+// filter returns the reports not contained in the baseline. On update
+// all reports get collected for the new baseline instead.
+func (b *baseline) filter(reports []report.ScriptCheckReport) []report.ScriptCheckReport {
+	if b.update {
+		for _, scriptReport := range reports {
+			b.current = append(b.current, newBaselineFinding(scriptReport))
+		}
+		return nil
+	}
+
+	newReports := make([]report.ScriptCheckReport, 0, len(reports))
+	for _, scriptReport := range reports {
+		fingerprint := newBaselineFinding(scriptReport).Fingerprint
+		if b.remaining[fingerprint] > 0 {
+			b.remaining[fingerprint]--
+			b.matchedCount++
+			continue
+		}
+
+		newReports = append(newReports, scriptReport)
+	}
+
+	return newReports
+}
+
+// close writes the updated baseline or reports the findings of the
+// baseline which are no longer found, unless only changes got checked
+func (b *baseline) close() error {
+	if b.update {
+		return b.write()
+	}
+
+	b.logger.Printf(
+		"Ignored %s finding(s) contained in the baseline %s",
+		color.Color(b.matchedCount, color.Bold),
+		color.Color(b.fileName, color.Bold),
+	)
+
+	if b.changesOnly {
+		return nil
+	}
+
+	fixedCount := 0
+	for _, finding := range b.findings {
+		if b.remaining[finding.Fingerprint] == 0 {
+			continue
+		}
+		b.remaining[finding.Fingerprint]--
+		fixedCount++
+
+		b.logger.Printf("Fixed baseline finding %s in %s: %s", finding.Reason, finding.File, finding.Snippet)
+	}
+
+	if fixedCount > 0 {
+		b.logger.Printf(
+			"%s finding(s) of the baseline are fixed, shrink it using --update-baseline",
+			color.Color(fixedCount, color.Bold),
+		)
+	}
+
+	return nil
+}
+
+func (b *baseline) write() error {
+	slices.SortFunc(b.current, func(a, b baselineFinding) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Job, b.Job),
+			cmp.Compare(a.Section, b.Section),
+			cmp.Compare(a.Reason, b.Reason),
+			cmp.Compare(a.Snippet, b.Snippet),
+		)
+	})
+
+	content, err := json.MarshalIndent(baselineFile{baselineVersion, b.current}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to write baseline: %w", err)
+	}
+
+	if err := os.WriteFile(b.fileName, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write baseline: %w", err)
+	}
+
+	b.logger.Printf(
+		"Wrote %s finding(s) into the baseline %s",
+		color.Color(len(b.current), color.Bold),
+		color.Color(b.fileName, color.Bold),
+	)
+
+	return nil
+}
+
+// newBaselineFinding creates the finding of the report, which is identified
+// by its file, job, section, rule and the normalized line of the script
+func newBaselineFinding(scriptReport report.ScriptCheckReport) baselineFinding {
+	snippet := scriptReport.Message
+	if scriptReport.Report.Line > 0 {
+		lines := strings.Split(scriptReport.Script.ScriptString(), "\n")
+		if scriptReport.Report.Line <= len(lines) {
+			snippet = lines[scriptReport.Report.Line-1]
+		}
+	}
+
+	finding := baselineFinding{
+		File:    filepath.ToSlash(filepath.Clean(scriptReport.File)),
+		Job:     scriptReport.Script.Job,
+		Section: scriptReport.Script.Section,
+		Reason:  scriptReport.Reason,
+		Snippet: strings.Join(strings.Fields(snippet), " "),
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		finding.File, finding.Job, finding.Section, finding.Reason, finding.Snippet,
+	}, "\x00")))
+	finding.Fingerprint = hex.EncodeToString(hash[:])
+
+	return finding
+}
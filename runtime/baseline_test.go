@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"path/filepath"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"testing"
+)
+
+func TestBaselineFilter(t *testing.T) {
+	baselineReport := func(script string, line int) report.ScriptCheckReport {
+		scriptBlock := exampleScript(script)
+		return report.ScriptCheckReport{
+			File:   "test",
+			Line:   line,
+			Reason: "SC2086",
+			Script: scriptBlock,
+			Report: report.ShellcheckReport{Line: scriptBlock.HeaderLines() + 1},
+		}
+	}
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Baseline = filepath.Join(t.TempDir(), "baseline.json")
+	options.UpdateBaseline = true
+
+	updated, err := newBaseline(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	updated.filter([]report.ScriptCheckReport{baselineReport("echo $A", 1), baselineReport("echo $B", 2)})
+	if err := updated.close(); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	options.UpdateBaseline = false
+	b, err := newBaseline(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	// findings are matched independent of their line and whitespace,
+	// each finding of the baseline matches a single finding only
+	reports := b.filter([]report.ScriptCheckReport{
+		baselineReport("echo  $A", 5),
+		baselineReport("echo $A", 6),
+		baselineReport("echo $C", 7),
+	})
+
+	if len(reports) != 2 || reports[0].Line != 6 || reports[1].Line != 7 {
+		t.Errorf("expected findings of line 6 and 7 to remain, got %v", reports)
+	}
+
+	if b.remaining[newBaselineFinding(baselineReport("echo $B", 2)).Fingerprint] != 1 {
+		t.Errorf("expected finding of the baseline to be fixed")
+	}
+}
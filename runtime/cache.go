@@ -0,0 +1,227 @@
+package runtime
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+	"time"
+)
+
+// default maximum size of the result cache in megabytes
+const DefaultCacheMaxSize = 100
+
+const cacheFileExtension = ".json"
+
+var shellcheckVersionRegex = regexp.MustCompile(`(?m)^version:\s*(\S+)`)
+
+// DefaultCacheDir returns the directory used to cache shellcheck results
+// in case no cache directory is configured
+func DefaultCacheDir() string {
+	if userCacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(userCacheDir, "scriptcheck")
+	}
+
+	return ""
+}
+
+// resultCache stores shellcheck results of single scripts on disk keyed
+// by the hash of the script content, the shellcheck version and all
+// arguments affecting the result. A nil cache is disabled.
+type resultCache struct {
+	directory string
+	maxSize   int64
+	logger    *log.Logger
+
+	// shellcheck version, arguments and configuration
+	// shared by all keys of the current run
+	salt string
+}
+
+func newResultCache(options *Options, args []string) *resultCache {
+	if options.NoCache || options.CacheDir == "" {
+		return nil
+	}
+
+	version, err := shellcheckVersion()
+	if err != nil {
+		if options.Debug {
+			options.logger().Printf("Disabling result cache, unable to detect shellcheck version: %s", err.Error())
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(options.CacheDir, os.ModePerm); err != nil {
+		if options.Debug {
+			options.logger().Printf("Disabling result cache, unable to create cache directory: %s", err.Error())
+		}
+		return nil
+	}
+
+	salt := new(strings.Builder)
+	salt.WriteString(version + "\x00")
+	salt.WriteString(strings.Join(args, "\x00") + "\x00")
+
+	// shellcheck configurations copied into the
+	// temporary directory affect the results too
+	for _, configFileName := range shellCheckConfigNames {
+		if config, err := os.ReadFile(configFileName); err == nil {
+			salt.Write(config)
+		}
+		salt.WriteString("\x00")
+	}
+
+	return &resultCache{
+		directory: options.CacheDir,
+		logger:    options.logger(),
+		maxSize:   int64(cmp.Or(options.CacheMaxSize, DefaultCacheMaxSize)) * 1024 * 1024,
+		salt:      salt.String(),
+	}
+}
+
+func shellcheckVersion() (string, error) {
+	out := new(bytes.Buffer)
+	cmd := exec.Command("shellcheck", "--version")
+	cmd.Stdout = out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	if match := shellcheckVersionRegex.FindStringSubmatch(out.String()); match != nil {
+		return match[1], nil
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+func (c *resultCache) path(script reader.ScriptBlock) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.salt))
+	hash.Write([]byte(script.ScriptString()))
+	return filepath.Join(c.directory, hex.EncodeToString(hash.Sum(nil))+cacheFileExtension)
+}
+
+// load returns the cached reports of the script
+func (c *resultCache) load(script reader.ScriptBlock) ([]report.ShellcheckReport, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	cachePath := c.path(script)
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, false
+	}
+
+	var reports []report.ShellcheckReport
+	if err := json.Unmarshal(content, &reports); err != nil {
+		return nil, false
+	}
+
+	// mark the entry as recently used for eviction
+	now := time.Now()
+	_ = os.Chtimes(cachePath, now, now)
+
+	return reports, true
+}
+
+// store stores the reports of the given files
+func (c *resultCache) store(
+	scriptMap map[string]reader.ScriptBlock,
+	fileNames []string,
+	reports []report.ShellcheckReport,
+) {
+	if c == nil || len(fileNames) == 0 {
+		return
+	}
+
+	fileReports := make(map[string][]report.ShellcheckReport)
+	for _, shellcheckReport := range reports {
+		fileReports[shellcheckReport.File] = append(fileReports[shellcheckReport.File], shellcheckReport)
+	}
+
+	for _, fileName := range fileNames {
+		// scripts without findings get cached as well
+		cachedReports := make([]report.ShellcheckReport, 0)
+		for _, shellcheckReport := range fileReports[fileName] {
+			shellcheckReport.File = ""
+			cachedReports = append(cachedReports, shellcheckReport)
+		}
+
+		if content, err := json.Marshal(cachedReports); err == nil {
+			_ = os.WriteFile(c.path(scriptMap[fileName]), content, 0644)
+		}
+	}
+}
+
+// trim evicts the least recently used entries
+// exceeding the maximum cache size
+func (c *resultCache) trim() {
+	if c == nil {
+		return
+	}
+
+	if err := c.evict(); err != nil {
+		c.logger.Printf("Unable to evict cache entries from %s: %s", color.Color(c.directory, color.Bold), err.Error())
+	}
+}
+
+// evict removes the least recently used entries until
+// the cache does not exceed its maximum size anymore
+func (c *resultCache) evict() error {
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return err
+	}
+
+	type cacheEntry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	cacheEntries := make([]cacheEntry, 0, len(entries))
+	var totalSize int64
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != cacheFileExtension {
+			continue
+		}
+
+		if info, err := entry.Info(); err == nil {
+			cacheEntries = append(cacheEntries, cacheEntry{
+				path:    filepath.Join(c.directory, entry.Name()),
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+			totalSize += info.Size()
+		}
+	}
+
+	slices.SortFunc(cacheEntries, func(a, b cacheEntry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	for _, entry := range cacheEntries {
+		if totalSize <= c.maxSize {
+			break
+		}
+
+		if err := os.Remove(entry.path); err != nil {
+			return err
+		}
+		totalSize -= entry.size
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// header of a hunk of a diff without context lines
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// changeSet contains the lines of yaml files changed since a git
+// reference, which limits the checked scripts and reported findings
+type changeSet struct {
+	// report all findings of changed scripts instead
+	// of the findings on changed lines only
+	wholeBlocks bool
+
+	// file name of the scripts read from stdin, which are changed entirely
+	stdinName string
+
+	// changed files by their slash separated name
+	files map[string]*fileChanges
+}
+
+type fileChanges struct {
+	// whether the file is untracked, thus every line is changed
+	untracked bool
+
+	// added or modified lines
+	changed map[int]bool
+	// lines surrounding removed lines
+	removed map[int]bool
+}
+
+// newChangeSet reads the changes between the merge base of the reference
+// and the working tree from the local repository, nil is returned in
+// case all files should be checked. Untracked files, which are not
+// ignored, are considered to be changed entirely.
+func newChangeSet(options *Options) (*changeSet, error) {
+	if options.ChangedSince == "" {
+		return nil, nil
+	}
+
+	if options.Merge {
+		return nil, errors.New("unable to check changes of merged files")
+	}
+
+	if options.UpdateBaseline {
+		return nil, errors.New("unable to update the baseline from changed scripts only")
+	}
+
+	diff, err := runGit(
+		"diff", "--merge-base", "--relative", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/",
+		"--unified=0", "--diff-filter=AMR", options.ChangedSince, "--",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read changes since %s: %w", options.ChangedSince, err)
+	}
+
+	changes, err := parseChanges(diff)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read changes since %s: %w", options.ChangedSince, err)
+	}
+	changes.wholeBlocks = options.ChangedBlocks
+	changes.stdinName = reader.StdinName(options.StdinFileName)
+
+	untracked, err := runGit("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read untracked files: %w", err)
+	}
+	for _, file := range strings.Split(untracked, "\x00") {
+		if file != "" {
+			changes.files[file] = &fileChanges{untracked: true}
+		}
+	}
+
+	return changes, nil
+}
+
+// runGit runs the git command inside the working directory and returns its output
+func runGit(args ...string) (string, error) {
+	out, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Stdout, cmd.Stderr = out, stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+
+	return out.String(), nil
+}
+
+// parseChanges reads the changed lines of all files from a unified diff
+func parseChanges(diff string) (*changeSet, error) {
+	changes := &changeSet{files: make(map[string]*fileChanges)}
+
+	var current *fileChanges
+	// added lines may look like file headers outside the header of a file
+	inHeader := false
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "diff --git ") {
+			inHeader = true
+			continue
+		}
+
+		if file, isFile := strings.CutPrefix(line, "+++ "); isFile && inHeader {
+			inHeader = false
+			current = nil
+			if file != "/dev/null" {
+				current = &fileChanges{changed: make(map[int]bool), removed: make(map[int]bool)}
+				// names containing spaces are terminated by a tab
+				changes.files[strings.TrimPrefix(strings.TrimSuffix(file, "\t"), "b/")] = current
+			}
+			continue
+		}
+
+		match := hunkHeaderRegex.FindStringSubmatch(line)
+		if match == nil || current == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+
+		// removed lines are located after the start line
+		if count == 0 {
+			current.removed[start] = true
+			current.removed[start+1] = true
+		}
+
+		for i := range count {
+			current.changed[start+i] = true
+		}
+	}
+
+	return changes, scanner.Err()
+}
+
+func (c *changeSet) fileChanges(file string) (*fileChanges, bool) {
+	changes, exists := c.files[filepath.ToSlash(filepath.Clean(file))]
+	return changes, exists
+}
+
+// isChanged reports whether the line got added or modified
+func (f *fileChanges) isChanged(line int) bool {
+	return f.untracked || f.changed[line]
+}
+
+// filterFiles returns the changed files, the reader.StdinFile is kept
+func (c *changeSet) filterFiles(files []string) []string {
+	if c == nil {
+		return files
+	}
+
+	return slices.DeleteFunc(files, func(file string) bool {
+		_, isChanged := c.fileChanges(file)
+		return file != reader.StdinFile && !isChanged
+	})
+}
+
+// filterScripts returns the scripts of all changed blocks, where a block
+// consists of all scripts of a section, like the elements of a sequence
+func (c *changeSet) filterScripts(scripts []reader.ScriptBlock) []reader.ScriptBlock {
+	if c == nil {
+		return scripts
+	}
+
+	changedBlocks := make(map[[2]string]bool)
+	for _, script := range scripts {
+		if c.isChanged(script) {
+			changedBlocks[[2]string{script.FileName, script.Path}] = true
+		}
+	}
+
+	return slices.DeleteFunc(scripts, func(script reader.ScriptBlock) bool {
+		return !changedBlocks[[2]string{script.FileName, script.Path}]
+	})
+}
+
+// isChanged reports whether the script contains any changed or
+// removed line, scripts read from stdin are changed entirely
+func (c *changeSet) isChanged(script reader.ScriptBlock) bool {
+	if script.FileName == c.stdinName {
+		return true
+	}
+
+	changes, exists := c.fileChanges(script.FileName)
+	if !exists {
+		return false
+	}
+
+	for line := script.StartPos; line <= script.EndLine(); line++ {
+		if changes.isChanged(line) || changes.removed[line] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterReports returns the reports on changed lines, or all reports
+// of changed scripts in case of whole blocks. All reports of scripts
+// read from stdin are kept.
+func (c *changeSet) filterReports(reports []report.ScriptCheckReport) []report.ScriptCheckReport {
+	if c.wholeBlocks {
+		return reports
+	}
+
+	return slices.DeleteFunc(reports, func(scriptReport report.ScriptCheckReport) bool {
+		if scriptReport.File == c.stdinName {
+			return false
+		}
+
+		changes, exists := c.fileChanges(scriptReport.File)
+		if !exists {
+			return true
+		}
+
+		for line := scriptReport.Line; line <= max(scriptReport.EndLine, scriptReport.Line); line++ {
+			if changes.isChanged(line) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"os"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"testing"
+)
+
+func TestParseChanges(t *testing.T) {
+	diff := `diff --git a/.gitlab-ci.yml b/.gitlab-ci.yml
+index 1111111..2222222 100644
+--- a/.gitlab-ci.yml
++++ b/.gitlab-ci.yml
+@@ -3 +3,2 @@ job:
+-    - echo $A
++    - echo "$A"
+++++ added line looking like a header
+@@ -10,2 +11,0 @@ other:
+diff --git a/removed.yml b/removed.yml
+--- a/removed.yml
++++ /dev/null
+`
+
+	changes, err := parseChanges(diff)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if len(changes.files) != 1 {
+		t.Fatalf("expected a single changed file, got %v", changes.files)
+	}
+
+	fileChanges, exists := changes.fileChanges("./.gitlab-ci.yml")
+	if !exists {
+		t.Fatalf("expected changes of .gitlab-ci.yml")
+	}
+
+	if !fileChanges.changed[3] || !fileChanges.changed[4] || fileChanges.changed[5] || len(fileChanges.changed) != 2 {
+		t.Errorf("expected lines 3 and 4 to be changed, got %v", fileChanges.changed)
+	}
+	if !fileChanges.removed[11] || !fileChanges.removed[12] {
+		t.Errorf("expected lines surrounding removed lines, got %v", fileChanges.removed)
+	}
+
+	reports := changes.filterReports([]report.ScriptCheckReport{
+		{File: ".gitlab-ci.yml", Line: 2, EndLine: 3},
+		{File: ".gitlab-ci.yml", Line: 5, EndLine: 5},
+		{File: "other.yml", Line: 3, EndLine: 3},
+	})
+	if len(reports) != 1 || reports[0].Line != 2 {
+		t.Errorf("expected the report overlapping changed lines only, got %v", reports)
+	}
+}
+
+func TestChangedStdin(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(workingDirectory) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if _, err := runGit("init", "--quiet"); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err := runGit("-c", "user.name=test", "-c", "user.email=test@example.org",
+		"commit", "--quiet", "--allow-empty", "--message", "initial"); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	original := os.Stdin
+	t.Cleanup(func() { os.Stdin = original })
+	os.Stdin = stdin
+	if _, err := input.WriteString("job:\n  script: echo $A\n"); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	_ = input.Close()
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.ChangedSince = "HEAD"
+	options.StdinFileName = "pipeline.yml"
+	changes, err := newChangeSet(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	scripts, _, err := collectAndExtractScripts(options, nil, changes)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if scripts = changes.filterScripts(scripts); len(scripts) != 1 {
+		t.Fatalf("expected the script read from stdin, got %v", scripts)
+	}
+
+	reports := changes.filterReports([]report.ScriptCheckReport{{File: scripts[0].FileName, Line: 2, EndLine: 2}})
+	if len(reports) != 1 {
+		t.Errorf("expected the report of the script read from stdin, got %v", reports)
+	}
+}
+
+func TestChangedBaseline(t *testing.T) {
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Baseline = "baseline.json"
+	options.ChangedSince = "HEAD"
+	options.UpdateBaseline = true
+
+	// the baseline would lose all findings of unchanged scripts
+	if _, err := newChangeSet(options); err == nil {
+		t.Errorf("expected updating the baseline from changes to fail")
+	}
+}
@@ -2,18 +2,21 @@ package runtime
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	goruntime "runtime"
 	"scriptcheck/color"
-	"scriptcheck/format"
 	"scriptcheck/reader"
 	"scriptcheck/report"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Possible names for a shellcheck configuration
@@ -21,108 +24,436 @@ import (
 // directory containing all extracted scripts
 var shellCheckConfigNames = []string{".shellcheckrc", "shellcheckrc"}
 
+// maximum number of scripts passed to a single shellcheck process
+const maxBatchSize = 100
+
 func CheckFiles(options *Options, globPatterns []string) error {
-	scripts, files, err := collectAndExtractScripts(options, globPatterns)
+	changes, err := newChangeSet(options)
+	if err != nil {
+		options.logger().Printf("Error reading changes: %v\n", err)
+		return err
+	}
+
+	scripts, files, err := collectAndExtractScripts(options, globPatterns, changes)
 	if err != nil {
 		return err
 	}
+	scripts = changes.filterScripts(scripts)
 
 	// if no scripts got found we can return directly
 	if len(scripts) == 0 {
 		return nil
 	}
 
-	log.Printf(
+	if (options.Fix || options.Diff) && (options.Merge || slices.Contains(files, reader.StdinFile)) {
+		return errors.New("unable to fix merged files or yaml read from stdin")
+	}
+
+	options.logger().Printf(
 		"Checking %s script(s) from %s file(s)...\n",
 		color.Color(len(scripts), color.Bold),
 		color.Color(len(files), color.Bold),
 	)
 
-	return checkScripts(options, scripts)
+	return checkScripts(context.Background(), options, scripts, changes)
+}
+
+// checkScripts checks the scripts and prints all reports, which
+// are limited to changed lines in case changes are given
+func checkScripts(ctx context.Context, options *Options, scripts []reader.ScriptBlock, changes *changeSet) error {
+	if options.Diff {
+		return diffFixes(ctx, options, scripts, changes)
+	}
+
+	baseline, err := newBaseline(options)
+	if err != nil {
+		return err
+	}
+
+	policy, err := newFailPolicy(options)
+	if err != nil {
+		return err
+	}
+
+	printer := newReportPrinter(options)
+	printer.policy = policy
+	printReports := printer.print
+	if baseline != nil {
+		printReports = func(reports []report.ScriptCheckReport) error {
+			return printer.print(baseline.filter(reports))
+		}
+	}
+	handleReports := printReports
+
+	// fixes can only be applied once all reports are known
+	var fixer *scriptFixer
+	if options.Fix {
+		fixer, err = newScriptFixer(options)
+		if err != nil {
+			return err
+		}
+		handleReports = fixer.collect
+	}
+
+	if changes != nil {
+		printChanged, handleChanged := printReports, handleReports
+		printReports = func(reports []report.ScriptCheckReport) error {
+			return printChanged(changes.filterReports(reports))
+		}
+		handleReports = func(reports []report.ScriptCheckReport) error {
+			return handleChanged(changes.filterReports(reports))
+		}
+	}
+
+	if err := runCheckers(ctx, options, scripts, handleReports); err != nil {
+		return err
+	}
+
+	if fixer != nil {
+		remainingReports, err := fixer.apply(scripts)
+		if err != nil {
+			return err
+		}
+		if err := printReports(remainingReports); err != nil {
+			return err
+		}
+	}
+
+	if options.RequireSuppressionReason {
+		if err := printReports(missingReasonReports(scripts)); err != nil {
+			return err
+		}
+	}
+
+	if options.UnusedSuppressions {
+		unusedReports, err := unusedSuppressionReports(ctx, options, scripts)
+		if err != nil {
+			return err
+		}
+		if err := printReports(unusedReports); err != nil {
+			return err
+		}
+	}
+
+	if baseline != nil {
+		if err := baseline.close(); err != nil {
+			return err
+		}
+	}
+
+	return printer.close()
+}
+
+// diffFixes writes the diff of all fixes into the output
+// without changing any file
+func diffFixes(ctx context.Context, options *Options, scripts []reader.ScriptBlock, changes *changeSet) error {
+	fixer, err := newScriptFixer(options)
+	if err != nil {
+		return err
+	}
+	collect := fixer.collect
+	if changes != nil {
+		collect = func(reports []report.ScriptCheckReport) error {
+			return fixer.collect(changes.filterReports(reports))
+		}
+	}
+
+	if err := runCheckers(ctx, options, scripts, collect); err != nil {
+		return err
+	}
+
+	remainingReports, err := fixer.apply(scripts)
+	if err != nil {
+		return err
+	}
+
+	if fixableCount := len(fixer.reports) - len(remainingReports); fixableCount > 0 {
+		return newScriptCheckError(fixableCount)
+	}
+
+	return nil
 }
 
-func checkScripts(options *Options, scripts []reader.ScriptBlock) error {
-	tempDir, fileScriptBlockMap, err := writeTempFiles(options, scripts)
+// runCheckers writes the scripts into a temporary directory and runs
+// the selected checker of every script against them. Shellcheck results
+// of unchanged scripts get reused from the result cache, if enabled.
+// Reports get passed to the handler batch by batch in the order of
+// the given scripts.
+func runCheckers(
+	ctx context.Context,
+	options *Options,
+	scripts []reader.ScriptBlock,
+	handleReports func([]report.ScriptCheckReport) error,
+) error {
+	tempDir, fileNames, fileScriptBlockMap, err := writeTempFiles(options, scripts)
 	if err != nil {
 		return err
 	}
 
-	defer removeIntermediateScripts(*tempDir)
+	defer removeIntermediateScripts(options, *tempDir)
 
 	// copy shellcheck configuration files
 	copyConfigFile(*tempDir)
 
-	fileNames := slices.Collect(maps.Keys(fileScriptBlockMap))
-	err = runShellcheck(options, fileNames, fileScriptBlockMap)
+	ruleLevels, err := newRuleLevels(options)
 	if err != nil {
 		return err
 	}
 
+	args := shellcheckArgs(options, fileScriptBlockMap)
+	checkers, err := newCheckerSelection(options, args)
+	if err != nil {
+		return err
+	}
+
+	// only shellcheck results are cached
+	var cache *resultCache
+	if checkers.uses(CheckerShellcheck) {
+		cache = newResultCache(options, args)
+		defer cache.trim()
+	}
+
+	var cachedCount atomic.Int64
+	checkBatch := func(ctx context.Context, batch []string) ([]report.ShellcheckReport, error) {
+		shellcheckReports := make([]report.ShellcheckReport, 0)
+		uncachedFileNames := make([]string, 0, len(batch))
+		for _, fileName := range batch {
+			script := fileScriptBlockMap[fileName]
+			if checkers.checkerType(script) != CheckerShellcheck {
+				uncachedFileNames = append(uncachedFileNames, fileName)
+			} else if cachedReports, exists := cache.load(script); exists {
+				for _, cachedReport := range cachedReports {
+					cachedReport.File = fileName
+					shellcheckReports = append(shellcheckReports, cachedReport)
+				}
+				cachedCount.Add(1)
+			} else {
+				uncachedFileNames = append(uncachedFileNames, fileName)
+			}
+		}
+
+		if len(uncachedFileNames) == 0 {
+			return shellcheckReports, nil
+		}
+
+		typeReports, err := checkers.check(ctx, uncachedFileNames, fileScriptBlockMap)
+		if err != nil {
+			return nil, err
+		}
+
+		for checkerType, checkerReports := range typeReports {
+			if checkerType == CheckerShellcheck {
+				shellcheckFileNames := slices.DeleteFunc(slices.Clone(uncachedFileNames), func(fileName string) bool {
+					return checkers.checkerType(fileScriptBlockMap[fileName]) != CheckerShellcheck
+				})
+				cache.store(fileScriptBlockMap, shellcheckFileNames, checkerReports)
+			}
+			shellcheckReports = append(shellcheckReports, checkerReports...)
+		}
+
+		return shellcheckReports, nil
+	}
+
+	handleBatch := func(shellcheckReports []report.ShellcheckReport) error {
+		scriptCheckReports := report.MapShellcheckReports(shellcheckReports, fileScriptBlockMap, ruleLevels)
+		report.SortReports(scriptCheckReports)
+		return handleReports(scriptCheckReports)
+	}
+
+	err = executeShellCheckBatches(ctx, options, fileNames, checkBatch, handleBatch)
+
+	if options.Debug && cache != nil {
+		options.logger().Printf(
+			"Reused cached results for %s script(s)",
+			color.Color(cachedCount.Load(), color.Bold),
+		)
+	}
+
+	return err
+}
+
+// executeShellCheckBatches splits the files into batches and checks every
+// batch concurrently, bounded by the configured number of jobs. Reports
+// get handled as soon as a batch and all of its preceding batches are
+// finished in order to keep the output independent of the execution order.
+func executeShellCheckBatches(
+	ctx context.Context,
+	options *Options,
+	fileNames []string,
+	checkBatch func(context.Context, []string) ([]report.ShellcheckReport, error),
+	handleBatch func([]report.ShellcheckReport) error,
+) error {
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = goruntime.NumCPU()
+	}
+
+	batches := batchFileNames(fileNames, jobs, maxBatchSize)
+	if options.Debug && len(batches) > 0 {
+		options.logger().Printf(
+			"Running shellcheck in %s batch(es) using %s job(s)",
+			color.Color(len(batches), color.Bold),
+			color.Color(jobs, color.Bold),
+		)
+	}
+
+	batchReports := make([][]report.ShellcheckReport, len(batches))
+	batchErrors := make([]error, len(batches))
+	batchDone := make([]chan struct{}, len(batches))
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// the first failing batch cancels all remaining ones
+	batchCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	semaphore := make(chan struct{}, jobs)
+	for i, batch := range batches {
+		batchDone[i] = make(chan struct{})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(batchDone[i])
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			// remaining batches are skipped once the context is done
+			if batchErrors[i] = batchCtx.Err(); batchErrors[i] == nil {
+				batchReports[i], batchErrors[i] = checkBatch(batchCtx, batch)
+				if batchErrors[i] != nil {
+					cancel(batchErrors[i])
+				}
+			}
+		}()
+	}
+
+	for i := range batches {
+		<-batchDone[i]
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if batchErrors[i] != nil {
+			return &CheckerError{Err: context.Cause(batchCtx)}
+		}
+
+		if err := handleBatch(batchReports[i]); err != nil {
+			return err
+		}
+
+		// reports of handled batches are not needed anymore
+		batchReports[i] = nil
+	}
+
 	return nil
 }
 
+// batchFileNames splits the files into batches evenly distributed across
+// all jobs, where a single batch does not exceed the maximum size in order
+// to avoid exceeding the argument limit of the shellcheck process
+func batchFileNames(fileNames []string, jobs, maxSize int) [][]string {
+	if len(fileNames) == 0 {
+		return nil
+	}
+
+	batchSize := (len(fileNames) + jobs - 1) / jobs
+	batchSize = max(1, min(batchSize, maxSize))
+
+	return slices.Collect(slices.Chunk(fileNames, batchSize))
+}
+
+// ScriptCheckError is returned in case of findings, where only findings
+// at or above the threshold of the fail policy count as failures
 type ScriptCheckError struct {
-	reports []report.ScriptCheckReport
+	reportCount int
+	failCount   int
+
+	// number of findings per level
+	levelCounts map[string]int
+}
+
+// newScriptCheckError returns the error of findings which all count as failures
+func newScriptCheckError(reportCount int) *ScriptCheckError {
+	return &ScriptCheckError{reportCount: reportCount, failCount: reportCount}
 }
 
 func (e ScriptCheckError) ReportCount() int {
-	return len(e.reports)
+	return e.reportCount
 }
 
-func (e ScriptCheckError) Error() string {
-	return fmt.Sprintf("Found %d issues", len(e.reports))
+// FailCount returns the number of findings at or above the threshold
+func (e ScriptCheckError) FailCount() int {
+	return e.failCount
 }
 
-func runShellcheck(options *Options, fileNames []string, scriptMap map[string]reader.ScriptBlock) error {
-	scriptCheckReports, err := executeShellCheckCommand(scriptMap, options, fileNames)
-	if err != nil {
-		return fmt.Errorf("unable to parse shellcheck report: %w", err)
-	}
+// LevelCounts returns the number of findings per level
+func (e ScriptCheckError) LevelCounts() map[string]int {
+	return maps.Clone(e.levelCounts)
+}
 
-	if len(scriptCheckReports) == 0 {
-		return nil
-	}
+func (e ScriptCheckError) Error() string {
+	return fmt.Sprintf("Found %d issues", e.reportCount)
+}
 
-	formatter := format.NewFormatter(options.Format)
-	reportString, err := formatter.Format(scriptCheckReports)
-	if err != nil {
-		return fmt.Errorf("unable to format shellcheck report: %w", err)
-	}
+// CheckerError is returned in case a checker fails to check the scripts,
+// like shellcheck not being installed or rejecting its arguments
+type CheckerError struct {
+	Err error
+}
 
-	reportWriter := NewReportWriter(options)
-	_, writerErr := reportWriter.WriteString(reportString)
-	if writerErr != nil {
-		return fmt.Errorf("unable to write shellcheck output: %w", err)
-	}
+func (e *CheckerError) Error() string {
+	return fmt.Sprintf("unable to check scripts: %v", e.Err)
+}
 
-	return &ScriptCheckError{scriptCheckReports}
+func (e *CheckerError) Unwrap() error {
+	return e.Err
 }
 
-func executeShellCheckCommand(scriptMap map[string]reader.ScriptBlock, options *Options, fileNames []string) ([]report.ScriptCheckReport, error) {
-	out := new(bytes.Buffer)
-	cmd := exec.Command("shellcheck", fileNames...)
-	cmd.Dir, _ = os.Getwd()
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = out
+// shellcheckArgs returns the arguments passed to shellcheck besides the files
+func shellcheckArgs(options *Options, scriptMap map[string]reader.ScriptBlock) []string {
+	args := make([]string, 0, len(options.ShellCheckArgs)+3)
 
 	// append provided shell args
 	for _, arg := range options.ShellCheckArgs {
-		cmd.Args = append(cmd.Args, "--"+arg)
+		args = append(args, "--"+arg)
+	}
+
+	// allow following sourced files in case scripts define source paths
+	if slices.ContainsFunc(slices.Collect(maps.Values(scriptMap)), reader.ScriptBlock.HasSourcePaths) {
+		args = append(args, "--external-sources")
 	}
 
-	// always force json format in order to parse it afterward
-	cmd.Args = append(cmd.Args, "--format", "json")
+	// always force json1 format in order to parse it afterward,
+	// which counts tabs as single column and provides fixes
+	return append(args, "--format", "json1")
+}
+
+func executeShellCheckCommand(ctx context.Context, args []string, fileNames []string) ([]report.ShellcheckReport, error) {
+	out, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, "shellcheck", fileNames...)
+	cmd.Dir, _ = os.Getwd()
+	cmd.Stderr = stderr
+	cmd.Stdout = out
+	cmd.Args = append(cmd.Args, args...)
 
 	if errors.Is(cmd.Err, exec.ErrDot) {
 		cmd.Err = nil
 	}
 
 	if runErr := cmd.Run(); runErr != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		var exitError *exec.ExitError
-		if errors.As(runErr, &exitError) && exitError.ExitCode() == 2 {
-			return nil, runErr
+		if !errors.As(runErr, &exitError) {
+			return nil, fmt.Errorf("unable to run shellcheck: %w", runErr)
+		}
+		if exitError.ExitCode() == 2 {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), runErr)
 		}
-		return report.NewScriptCheckReport(out.Bytes(), scriptMap)
+		return report.ParseShellcheckReports(out.Bytes())
 	} else {
 		// nothing to do in this case
 		return nil, nil
@@ -142,40 +473,48 @@ func copyConfigFile(destDir string) {
 	}
 }
 
-func writeTempFiles(options *Options, scripts []reader.ScriptBlock) (*string, map[string]reader.ScriptBlock, error) {
+// writeTempFiles writes the scripts into a temporary directory and returns
+// the names of the written files in the order of the given scripts
+func writeTempFiles(options *Options, scripts []reader.ScriptBlock) (*string, []string, map[string]reader.ScriptBlock, error) {
 	tempDir, err := os.MkdirTemp("", "scripts")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create temp dir: %s", err.Error())
+		return nil, nil, nil, fmt.Errorf("failed to create temp dir: %s", err.Error())
 	}
 
 	if options.Debug {
-		log.Printf(
+		options.logger().Printf(
 			"Created intermediate directory %s",
 			color.Color(tempDir, color.Bold),
 		)
 	}
 
 	scriptWriter := NewTempDirScriptWriter(tempDir)
-	fileScriptMap, err := writeReports(scriptWriter, scripts)
+	fileNames, fileScriptMap, err := writeReports(scriptWriter, scripts)
+	if err != nil {
+		removeIntermediateScripts(options, tempDir)
+		return nil, nil, nil, err
+	}
 
-	return &tempDir, fileScriptMap, err
+	return &tempDir, fileNames, fileScriptMap, nil
 }
 
-func writeReports(scriptWriter ScriptWriter, scripts []reader.ScriptBlock) (map[string]reader.ScriptBlock, error) {
-	var fileNames = make(map[string]reader.ScriptBlock)
+func writeReports(scriptWriter ScriptWriter, scripts []reader.ScriptBlock) ([]string, map[string]reader.ScriptBlock, error) {
+	var fileNames = make([]string, 0, len(scripts))
+	var fileScriptMap = make(map[string]reader.ScriptBlock)
 	for _, script := range scripts {
 		file, err := scriptWriter.WriteScript(script)
 		if err != nil {
-			return nil, fmt.Errorf("unable to create temporary file %w", err)
+			return nil, nil, fmt.Errorf("unable to create temporary file %w", err)
 		}
-		fileNames[file.Name()] = script
+		fileNames = append(fileNames, file.Name())
+		fileScriptMap[file.Name()] = script
 	}
 
-	return fileNames, nil
+	return fileNames, fileScriptMap, nil
 }
 
-func removeIntermediateScripts(path string) {
-	log.Printf(
+func removeIntermediateScripts(options *Options, path string) {
+	options.logger().Printf(
 		"Removing intermediate directory %s",
 		color.Color(path, color.Bold),
 	)
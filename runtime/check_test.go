@@ -1,12 +1,18 @@
 package runtime
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/goccy/go-yaml/ast"
 	"github.com/goccy/go-yaml/token"
 	"scriptcheck/format"
 	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestChecking(t *testing.T) {
@@ -28,7 +34,7 @@ func TestChecking(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		err := checkScripts(c.options, []reader.ScriptBlock{c.script})
+		err := checkScripts(context.Background(), c.options, []reader.ScriptBlock{c.script}, nil)
 		var scriptCheckError *ScriptCheckError
 		if errors.As(err, &scriptCheckError) == c.expectSuccess {
 			t.Errorf("error should be ScriptCheckError")
@@ -59,3 +65,98 @@ func exampleScript(script string) reader.ScriptBlock {
 		nil,
 	)
 }
+
+func TestBatchFileNames(t *testing.T) {
+	fileNames := make([]string, 10)
+	for i := range fileNames {
+		fileNames[i] = fmt.Sprintf("script-%d", i)
+	}
+
+	cases := []struct {
+		jobs, maxSize int
+		batchSizes    []int
+	}{
+		{jobs: 1, maxSize: 100, batchSizes: []int{10}},
+		{jobs: 3, maxSize: 100, batchSizes: []int{4, 4, 2}},
+		{jobs: 2, maxSize: 3, batchSizes: []int{3, 3, 3, 1}},
+		{jobs: 32, maxSize: 100, batchSizes: []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+	}
+
+	for _, c := range cases {
+		batches := batchFileNames(fileNames, c.jobs, c.maxSize)
+		batchSizes := make([]int, 0, len(batches))
+		for _, batch := range batches {
+			batchSizes = append(batchSizes, len(batch))
+		}
+
+		if !slices.Equal(batchSizes, c.batchSizes) {
+			t.Errorf("jobs %d, max size %d: expected batches %v, got %v", c.jobs, c.maxSize, c.batchSizes, batchSizes)
+		}
+	}
+}
+
+func TestExecuteShellCheckBatchesOrder(t *testing.T) {
+	fileNames := make([]string, 10)
+	for i := range fileNames {
+		fileNames[i] = fmt.Sprintf("script-%d", i)
+	}
+
+	// later batches finish first
+	checkBatch := func(_ context.Context, batch []string) ([]report.ShellcheckReport, error) {
+		index := slices.Index(fileNames, batch[0])
+		time.Sleep(time.Duration(len(fileNames)-index) * time.Millisecond)
+		return []report.ShellcheckReport{{File: batch[0]}}, nil
+	}
+
+	handledFileNames := make([]string, 0)
+	handleBatch := func(reports []report.ShellcheckReport) error {
+		for _, shellcheckReport := range reports {
+			handledFileNames = append(handledFileNames, shellcheckReport.File)
+		}
+		return nil
+	}
+
+	err := executeShellCheckBatches(context.Background(), &Options{Jobs: 10}, fileNames, checkBatch, handleBatch)
+	if err != nil {
+		t.Errorf("unexpected error %s", err.Error())
+	}
+
+	if !slices.Equal(handledFileNames, fileNames) {
+		t.Errorf("expected batches handled in order %v, got %v", fileNames, handledFileNames)
+	}
+}
+
+func TestExecuteShellCheckBatchesError(t *testing.T) {
+	fileNames := make([]string, 10)
+	for i := range fileNames {
+		fileNames[i] = fmt.Sprintf("script-%d", i)
+	}
+
+	// the last batch fails, while all preceding ones run until they get canceled
+	failure := errors.New("failure")
+	var uncanceled atomic.Int64
+	checkBatch := func(ctx context.Context, batch []string) ([]report.ShellcheckReport, error) {
+		if batch[0] == fileNames[len(fileNames)-1] {
+			return nil, failure
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			uncanceled.Add(1)
+			return nil, nil
+		}
+	}
+
+	handleBatch := func([]report.ShellcheckReport) error { return nil }
+
+	err := executeShellCheckBatches(context.Background(), &Options{Jobs: 10}, fileNames, checkBatch, handleBatch)
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the failed batch, got %v", err)
+	}
+
+	if uncanceled.Load() > 0 {
+		t.Errorf("expected remaining batches to be canceled, %d batch(es) ran to completion", uncanceled.Load())
+	}
+}
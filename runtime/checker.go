@@ -0,0 +1,324 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"mvdan.cc/sh/v3/syntax"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type CheckerType string
+
+const (
+	// CheckerShellcheck runs shellcheck against the scripts
+	CheckerShellcheck CheckerType = "shellcheck"
+	// CheckerSyntax runs the installed interpreters in no-exec mode
+	CheckerSyntax CheckerType = "syntax"
+	// CheckerParse parses the scripts without requiring any tool
+	CheckerParse CheckerType = "parse"
+)
+
+// CheckerTypes lists all available checkers
+var CheckerTypes = []CheckerType{CheckerShellcheck, CheckerSyntax, CheckerParse}
+
+// reason of syntax errors found by the syntax and parse checker
+const SyntaxErrorReason = "SX1001"
+
+// dialect assumed for scripts without shell and shebang
+const defaultDialect = "sh"
+
+// Checker checks scripts written into files. Reports refer to the
+// checked files and get mapped to the yaml files afterward. Checking
+// stops once the context is done.
+type Checker interface {
+	Check(ctx context.Context, fileNames []string, scriptMap map[string]reader.ScriptBlock) ([]report.ShellcheckReport, error)
+}
+
+func newChecker(checkerType CheckerType, options *Options, shellcheckArgs []string) (Checker, error) {
+	switch checkerType {
+	case CheckerShellcheck:
+		return &shellcheckChecker{args: shellcheckArgs}, nil
+	case CheckerSyntax:
+		return &syntaxChecker{debug: options.Debug, logger: options.logger()}, nil
+	case CheckerParse:
+		return &parseChecker{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown checker %s", checkerType)
+}
+
+// checkerSelection selects the checker of a script by its shell dialect
+type checkerSelection struct {
+	defaultType  CheckerType
+	dialectTypes map[string]CheckerType
+
+	checkers map[CheckerType]Checker
+}
+
+func newCheckerSelection(options *Options, shellcheckArgs []string) (*checkerSelection, error) {
+	selection := &checkerSelection{
+		defaultType:  cmp.Or(options.Checker, CheckerShellcheck),
+		dialectTypes: make(map[string]CheckerType),
+		checkers:     make(map[CheckerType]Checker),
+	}
+
+	for dialect, checkerType := range options.DialectCheckers {
+		selection.dialectTypes[dialect] = CheckerType(checkerType)
+	}
+
+	for _, checkerType := range selection.types() {
+		checker, err := newChecker(checkerType, options, shellcheckArgs)
+		if err != nil {
+			return nil, err
+		}
+		selection.checkers[checkerType] = checker
+	}
+
+	return selection, nil
+}
+
+// types returns all checker types used by the selection
+func (s *checkerSelection) types() []CheckerType {
+	types := []CheckerType{s.defaultType}
+	for _, checkerType := range s.dialectTypes {
+		if !slices.Contains(types, checkerType) {
+			types = append(types, checkerType)
+		}
+	}
+
+	slices.Sort(types)
+	return types
+}
+
+func (s *checkerSelection) uses(checkerType CheckerType) bool {
+	return slices.Contains(s.types(), checkerType)
+}
+
+// checkerType returns the type of the checker checking the script
+func (s *checkerSelection) checkerType(script reader.ScriptBlock) CheckerType {
+	if checkerType, exists := s.dialectTypes[scriptDialect(script)]; exists {
+		return checkerType
+	}
+
+	return s.defaultType
+}
+
+// check checks the files grouped by their checker
+func (s *checkerSelection) check(
+	ctx context.Context,
+	fileNames []string,
+	scriptMap map[string]reader.ScriptBlock,
+) (map[CheckerType][]report.ShellcheckReport, error) {
+	typeFileNames := make(map[CheckerType][]string)
+	for _, fileName := range fileNames {
+		checkerType := s.checkerType(scriptMap[fileName])
+		typeFileNames[checkerType] = append(typeFileNames[checkerType], fileName)
+	}
+
+	typeReports := make(map[CheckerType][]report.ShellcheckReport)
+	for _, checkerType := range slices.Sorted(maps.Keys(typeFileNames)) {
+		reports, err := s.checkers[checkerType].Check(ctx, typeFileNames[checkerType], scriptMap)
+		if err != nil {
+			return nil, err
+		}
+		typeReports[checkerType] = reports
+	}
+
+	return typeReports, nil
+}
+
+// scriptDialect returns the shell dialect of the script given by
+// its shell directive or shebang, falling back to the default dialect
+func scriptDialect(script reader.ScriptBlock) string {
+	if script.HasShell() {
+		return script.Shell
+	}
+
+	firstLine, _, _ := strings.Cut(string(script.Script), "\n")
+	if shebang, isShebang := strings.CutPrefix(firstLine, "#!"); isShebang {
+		fields := strings.Fields(shebang)
+		if len(fields) > 1 && filepath.Base(fields[0]) == "env" {
+			return filepath.Base(fields[1])
+		}
+		if len(fields) > 0 {
+			return filepath.Base(fields[0])
+		}
+	}
+
+	return defaultDialect
+}
+
+// shellcheckChecker runs shellcheck for all files within a single process
+type shellcheckChecker struct {
+	args []string
+}
+
+func (c *shellcheckChecker) Check(ctx context.Context, fileNames []string, _ map[string]reader.ScriptBlock) ([]report.ShellcheckReport, error) {
+	return executeShellCheckCommand(ctx, c.args, fileNames)
+}
+
+// interpreters able to check the syntax of the
+// dialect ordered by preference, where the
+// file name gets appended to the arguments
+var syntaxInterpreters = map[string][][]string{
+	"sh":      {{"dash", "-n"}, {"busybox", "sh", "-n"}, {"sh", "-n"}},
+	"dash":    {{"dash", "-n"}},
+	"bash":    {{"bash", "-n"}},
+	"ksh":     {{"ksh", "-n"}},
+	"busybox": {{"busybox", "sh", "-n"}},
+}
+
+// syntaxChecker runs the installed interpreter of every script in
+// no-exec mode, scripts without installed interpreter get skipped
+type syntaxChecker struct {
+	debug  bool
+	logger *log.Logger
+
+	skippedDialects sync.Map
+}
+
+func (c *syntaxChecker) Check(ctx context.Context, fileNames []string, scriptMap map[string]reader.ScriptBlock) ([]report.ShellcheckReport, error) {
+	reports := make([]report.ShellcheckReport, 0)
+	for _, fileName := range fileNames {
+		dialect := scriptDialect(scriptMap[fileName])
+		interpreter := c.interpreter(dialect)
+		if interpreter == nil {
+			if _, skipped := c.skippedDialects.LoadOrStore(dialect, true); !skipped && c.debug {
+				c.logger.Printf("Skipping syntax check of %s scripts, no interpreter installed", color.Color(dialect, color.Bold))
+			}
+			continue
+		}
+
+		fileReports, err := executeSyntaxCheck(ctx, interpreter, fileName)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, fileReports...)
+	}
+
+	return reports, nil
+}
+
+func (c *syntaxChecker) interpreter(dialect string) []string {
+	for _, interpreter := range syntaxInterpreters[dialect] {
+		if _, err := exec.LookPath(interpreter[0]); err == nil {
+			return interpreter
+		}
+	}
+
+	return nil
+}
+
+// error written by an interpreter, like "file: line 3: message" or "file: 3: message"
+var syntaxErrorRegex = regexp.MustCompile(`^(.*?):\s*(?:line\s+)?(\d+):\s*(.*)$`)
+
+// executeSyntaxCheck runs the interpreter and parses the errors
+// written to stderr, like "file: line 3: syntax error: ..."
+func executeSyntaxCheck(ctx context.Context, interpreter []string, fileName string) ([]report.ShellcheckReport, error) {
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, interpreter[0], append(slices.Clone(interpreter[1:]), fileName)...)
+	cmd.Stderr = stderr
+
+	if runErr := cmd.Run(); runErr != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var exitError *exec.ExitError
+		if !errors.As(runErr, &exitError) {
+			return nil, fmt.Errorf("unable to run %s: %w", interpreter[0], runErr)
+		}
+	}
+
+	reports := make([]report.ShellcheckReport, 0)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		// the file may be prefixed by the name of the interpreter
+		match := syntaxErrorRegex.FindStringSubmatch(scanner.Text())
+		if match == nil || !strings.HasSuffix(match[1], fileName) {
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[2])
+		reports = append(reports, syntaxErrorReport(fileName, line, 1, match[3]))
+	}
+
+	return reports, nil
+}
+
+// parseChecker parses the scripts using the shell parser of mvdan.cc/sh
+type parseChecker struct{}
+
+// parser language of every dialect, unknown dialects are parsed as bash
+var parseLanguages = map[string]syntax.LangVariant{
+	"sh":      syntax.LangPOSIX,
+	"dash":    syntax.LangPOSIX,
+	"busybox": syntax.LangPOSIX,
+	"ksh":     syntax.LangMirBSDKorn,
+	"mksh":    syntax.LangMirBSDKorn,
+	"bash":    syntax.LangBash,
+	"bats":    syntax.LangBats,
+}
+
+func (c *parseChecker) Check(ctx context.Context, fileNames []string, scriptMap map[string]reader.ScriptBlock) ([]report.ShellcheckReport, error) {
+	reports := make([]report.ShellcheckReport, 0)
+	for _, fileName := range fileNames {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read script: %w", err)
+		}
+
+		language := parseLanguages[scriptDialect(scriptMap[fileName])]
+		parser := syntax.NewParser(syntax.Variant(language))
+
+		_, err = parser.Parse(bytes.NewReader(content), "")
+		var parseError syntax.ParseError
+		var langError syntax.LangError
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &parseError):
+			reports = append(reports, syntaxErrorReport(fileName, int(parseError.Pos.Line()), int(parseError.Pos.Col()), parseError.Text))
+		case errors.As(err, &langError):
+			_, message, _ := strings.Cut(langError.Error(), ": ")
+			reports = append(reports, syntaxErrorReport(fileName, int(langError.Pos.Line()), int(langError.Pos.Col()), message))
+		default:
+			return nil, fmt.Errorf("unable to parse script: %w", err)
+		}
+	}
+
+	return reports, nil
+}
+
+func syntaxErrorReport(fileName string, line, column int, message string) report.ShellcheckReport {
+	return report.ShellcheckReport{
+		File:      fileName,
+		Line:      line,
+		EndLine:   line,
+		Column:    max(1, column),
+		EndColumn: max(1, column),
+		Level:     "error",
+		Reason:    SyntaxErrorReason,
+		Message:   message,
+	}
+}
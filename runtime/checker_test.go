@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"context"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/token"
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"testing"
+)
+
+func TestScriptDialect(t *testing.T) {
+	cases := []struct {
+		script  string
+		shell   string
+		dialect string
+	}{
+		{script: "echo test", dialect: "sh"},
+		{script: "echo test", shell: "bash", dialect: "bash"},
+		{script: "#!/bin/dash\necho test", dialect: "dash"},
+		{script: "#!/usr/bin/env bash\necho test", dialect: "bash"},
+	}
+
+	for _, c := range cases {
+		script := reader.NewScriptBlock(
+			"test",
+			"key",
+			c.shell,
+			reader.ScriptNode{Script: reader.Script(c.script), Line: 1},
+			ast.String(token.String("example", "org", &token.Position{})),
+			nil,
+		)
+
+		if dialect := scriptDialect(script); dialect != c.dialect {
+			t.Errorf("%q: expected dialect %s, got %s", c.script, c.dialect, dialect)
+		}
+	}
+}
+
+func TestParseChecker(t *testing.T) {
+	cases := []struct {
+		script      string
+		reportLines []int
+	}{
+		{script: "echo test\n", reportLines: nil},
+		{script: "echo test\nif true; then\n", reportLines: []int{3}},
+		{script: "arr=(a b)\n", reportLines: []int{2}},
+	}
+
+	for _, c := range cases {
+		script := exampleScript(c.script)
+		fileName := filepath.Join(t.TempDir(), "script")
+		if err := os.WriteFile(fileName, []byte(script.ScriptString()), 0644); err != nil {
+			t.Fatalf("unable to write script: %s", err)
+		}
+
+		reports, err := (&parseChecker{}).Check(context.Background(), []string{fileName}, map[string]reader.ScriptBlock{fileName: script})
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", c.script, err)
+			continue
+		}
+
+		if len(reports) != len(c.reportLines) {
+			t.Errorf("%q: expected %d report(s), got %d", c.script, len(c.reportLines), len(reports))
+			continue
+		}
+
+		for i, checkReport := range reports {
+			if checkReport.Line != c.reportLines[i] || checkReport.Reason != SyntaxErrorReason {
+				t.Errorf("%q: unexpected report %+v", c.script, checkReport)
+			}
+		}
+	}
+}
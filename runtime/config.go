@@ -0,0 +1,205 @@
+package runtime
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/goccy/go-yaml"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+)
+
+// ConfigFileNames lists the names of project configuration
+// files, which are searched up to the root of the repository
+var ConfigFileNames = []string{".scriptcheck.yml", ".scriptcheck.yaml"}
+
+// keys of the configuration file which are not the name of a flag
+var configKeys = []string{"include", "exclude", "overrides", "profiles"}
+
+// Config contains the values of a project configuration file
+type Config struct {
+	// patterns of files checked when no pattern is given
+	// and patterns of files excluded from checking
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	Overrides []Override         `yaml:"overrides"`
+	Profiles  map[string]Profile `yaml:"profiles"`
+
+	// values of flags by the name of the flag or values of
+	// flags of a single command by the name of the command
+	Values map[string]any `yaml:"-"`
+}
+
+// Override defines the shell, disabled rules and severity of all
+// scripts of files and jobs matching any of the glob patterns, unless
+// they are defined by a scriptcheck directive of the script
+type Override struct {
+	Files []string `yaml:"files"`
+	Jobs  []string `yaml:"jobs"`
+
+	Shell    string   `yaml:"shell"`
+	Disable  []string `yaml:"disable"`
+	Severity string   `yaml:"severity"`
+}
+
+// FindConfig searches a configuration file in the given directory and its
+// parents up to the root of the git repository, an empty file name is
+// returned if no configuration file exists
+func FindConfig(directory string) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", fmt.Errorf("unable to search configuration: %w", err)
+	}
+
+	for {
+		for _, name := range ConfigFileNames {
+			file := filepath.Join(directory, name)
+			if _, err := os.Stat(file); err == nil {
+				return file, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("unable to search configuration: %w", err)
+			}
+		}
+
+		// the root of the repository ends the search
+		if _, err := os.Stat(filepath.Join(directory, ".git")); err == nil {
+			return "", nil
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return "", nil
+		}
+		directory = parent
+	}
+}
+
+// LoadConfig reads and validates the given configuration file
+func LoadConfig(file string) (*Config, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration: %w", err)
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("unable to parse configuration %s: %w", file, err)
+	}
+
+	if err := yaml.Unmarshal(content, &config.Values); err != nil {
+		return nil, fmt.Errorf("unable to parse configuration %s: %w", file, err)
+	}
+	for _, key := range configKeys {
+		delete(config.Values, key)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", file, err)
+	}
+
+	if err := config.relativizePatterns(filepath.Dir(file)); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// relativizePatterns changes the file patterns, which are relative to the
+// directory of the configuration, to be relative to the working directory
+func (c *Config) relativizePatterns(directory string) error {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("unable to resolve configuration patterns: %w", err)
+	}
+
+	directory, err = filepath.Abs(directory)
+	if err != nil {
+		return fmt.Errorf("unable to resolve configuration patterns: %w", err)
+	}
+
+	relativeDirectory, err := filepath.Rel(workingDirectory, directory)
+	if err != nil || relativeDirectory == "." {
+		return nil
+	}
+
+	relativize := func(patterns []string) []string {
+		relativePatterns := make([]string, 0, len(patterns))
+		for _, pattern := range patterns {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.ToSlash(filepath.Join(relativeDirectory, pattern))
+			}
+			relativePatterns = append(relativePatterns, pattern)
+		}
+		return relativePatterns
+	}
+
+	c.Include = relativize(c.Include)
+	c.Exclude = relativize(c.Exclude)
+	for i := range c.Overrides {
+		c.Overrides[i].Files = relativize(c.Overrides[i].Files)
+	}
+
+	return nil
+}
+
+func (c *Config) validate() error {
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid pattern %s", pattern)
+		}
+	}
+
+	for _, override := range c.Overrides {
+		for _, pattern := range slices.Concat(override.Files, override.Jobs) {
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("invalid override pattern %s", pattern)
+			}
+		}
+
+		if override.Severity != "" && !slices.Contains(report.Levels, override.Severity) {
+			return fmt.Errorf("unknown override severity %s, expected one of %v", override.Severity, report.Levels)
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether the override applies to the script, patterns
+// of files and jobs need to match if any pattern is given
+func (o Override) matches(script reader.ScriptBlock) bool {
+	file := filepath.ToSlash(filepath.Clean(script.FileName))
+	matchesFile := len(o.Files) == 0 || slices.ContainsFunc(o.Files, func(pattern string) bool {
+		return doublestar.MatchUnvalidated(pattern, file)
+	})
+	matchesJob := len(o.Jobs) == 0 || slices.ContainsFunc(o.Jobs, func(pattern string) bool {
+		return doublestar.MatchUnvalidated(pattern, script.Job)
+	})
+
+	return matchesFile && matchesJob
+}
+
+// applyOverrides applies all matching overrides to the scripts,
+// where later overrides take precedence over earlier ones
+func applyOverrides(overrides []Override, scripts []reader.ScriptBlock) {
+	for i, script := range scripts {
+		var shell, severity string
+		disabledRules := make([]string, 0)
+		for _, override := range overrides {
+			if !override.matches(script) {
+				continue
+			}
+
+			shell = cmp.Or(override.Shell, shell)
+			severity = cmp.Or(override.Severity, severity)
+			disabledRules = append(disabledRules, override.Disable...)
+		}
+
+		scripts[i] = script.WithDefaults(shell, disabledRules, severity)
+	}
+}
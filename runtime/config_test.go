@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.Mkdir(filepath.Join(directory, ".git"), 0755); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	content := `
+strict: true
+check:
+  format: json
+include: ["**/*.yml"]
+overrides:
+  - jobs: ["deploy*"]
+    shell: bash
+    disable: [SC2086]
+  - files: ["**/legacy.yml"]
+    severity: error
+`
+	configFile := filepath.Join(directory, ".scriptcheck.yml")
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	nested := filepath.Join(directory, "nested")
+	if err := os.Mkdir(nested, 0755); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	found, err := FindConfig(nested)
+	if err != nil || found != configFile {
+		t.Fatalf("expected configuration %s to be found, got %s (%v)", configFile, found, err)
+	}
+
+	config, err := LoadConfig(found)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if config.Values["strict"] != true || len(config.Values) != 2 {
+		t.Errorf("expected flag values strict and check, got %v", config.Values)
+	}
+
+	scripts := []reader.ScriptBlock{exampleScript("echo $A"), exampleScript("echo $A")}
+	scripts[0].Job = "deploy-prod"
+	applyOverrides(config.Overrides, scripts)
+
+	if scripts[0].Shell != "bash" || !strings.HasPrefix(scripts[0].ScriptString(), "# shellcheck shell=bash disable=SC2086\n") {
+		t.Errorf("expected override of deploy job, got %q", scripts[0].ScriptString())
+	}
+	if scripts[1].Shell != "sh" || len(scripts[1].Suppressions()) != 0 {
+		t.Errorf("expected no override of other job, got %q", scripts[1].ScriptString())
+	}
+
+	if err := os.WriteFile(configFile, []byte("overrides:\n  - severity: fatal\n"), 0644); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err := LoadConfig(configFile); err == nil {
+		t.Errorf("expected error for unknown severity")
+	}
+}
@@ -0,0 +1,161 @@
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// number of unchanged lines surrounding changes of a diff
+const diffContextLines = 3
+
+type diffOperation struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the unified diff between the original
+// and the changed content of the given file
+func unifiedDiff(fileName, original, changed string) string {
+	if original == changed {
+		return ""
+	}
+
+	operations := diffLines(strings.SplitAfter(original, "\n"), strings.SplitAfter(changed, "\n"))
+
+	builder := new(strings.Builder)
+	if filepath.IsAbs(fileName) {
+		builder.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fileName, fileName))
+	} else {
+		builder.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", fileName, fileName))
+	}
+
+	for start := 0; start < len(operations); {
+		firstChange := slices.IndexFunc(operations[start:], isDiffChange)
+		if firstChange < 0 {
+			break
+		}
+		firstChange += start
+
+		// extend the hunk as long as changes are close to each other
+		lastChange := firstChange
+		for i := firstChange + 1; i < len(operations) && i <= lastChange+2*diffContextLines; i++ {
+			if isDiffChange(operations[i]) {
+				lastChange = i
+			}
+		}
+
+		hunkStart := max(start, firstChange-diffContextLines)
+		hunkEnd := min(len(operations), lastChange+diffContextLines+1)
+
+		originalLine, changedLine := 1, 1
+		for _, operation := range operations[:hunkStart] {
+			if operation.kind != '+' {
+				originalLine++
+			}
+			if operation.kind != '-' {
+				changedLine++
+			}
+		}
+
+		originalCount, changedCount := 0, 0
+		for _, operation := range operations[hunkStart:hunkEnd] {
+			if operation.kind != '+' {
+				originalCount++
+			}
+			if operation.kind != '-' {
+				changedCount++
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", originalLine, originalCount, changedLine, changedCount))
+		for _, operation := range operations[hunkStart:hunkEnd] {
+			builder.WriteByte(operation.kind)
+			builder.WriteString(operation.line)
+			if !strings.HasSuffix(operation.line, "\n") {
+				builder.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = hunkEnd
+	}
+
+	return builder.String()
+}
+
+func isDiffChange(operation diffOperation) bool {
+	return operation.kind != ' '
+}
+
+// diffLines computes the shortest edit script between the lines
+// using the algorithm of Myers, which is fast for small changes
+func diffLines(a, b []string) []diffOperation {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	trace := make([][]int, 0)
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	operations := make([]diffOperation, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+
+		previousX := v[offset+previousK]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			operations = append(operations, diffOperation{' ', a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == previousX {
+				operations = append(operations, diffOperation{'+', b[y-1]})
+				y--
+			} else {
+				operations = append(operations, diffOperation{'-', a[x-1]})
+				x--
+			}
+		}
+	}
+
+	slices.Reverse(operations)
+
+	// the last element of split lines is empty for content ending with a line break
+	return slices.DeleteFunc(operations, func(operation diffOperation) bool {
+		return operation.line == ""
+	})
+}
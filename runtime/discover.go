@@ -0,0 +1,195 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"scriptcheck/reader"
+	"slices"
+	"strings"
+)
+
+// IgnoreFileNames lists the files containing gitignore patterns of
+// files skipped when discovering files or matching glob patterns
+var IgnoreFileNames = []string{".gitignore", ".scriptcheckignore"}
+
+// names of directories containing vendored or generated files
+var vendoredDirectories = []string{".git", "node_modules", "vendor", "third_party", "bower_components"}
+
+// discoverFiles walks the working directory and returns all
+// pipeline files of the pipeline type found by convention
+func discoverFiles(options *Options) ([]string, error) {
+	patterns := reader.PipelineFilePatterns(options.PipelineType)
+	ignore := newIgnoreMatcher()
+
+	files := make([]string, 0)
+	err := filepath.WalkDir(".", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if file == "." {
+			return nil
+		}
+
+		if entry.IsDir() {
+			if ignore.isSkipped(file, true) || isExcluded(options.Exclude, file) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		slashFile := filepath.ToSlash(file)
+		if slices.ContainsFunc(patterns, func(pattern string) bool {
+			return doublestar.MatchUnvalidated(pattern, slashFile)
+		}) && !ignore.isSkipped(file, false) && !isExcluded(options.Exclude, file) {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover pipeline files: %w", err)
+	}
+
+	return files, nil
+}
+
+// ignoreRule is a single pattern of an ignore file
+type ignoreRule struct {
+	pattern string
+	negated bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches files against the patterns of all ignore
+// files inside the working directory, which are read on demand
+type ignoreMatcher struct {
+	// rules by the directory of their ignore file
+	rules map[string][]ignoreRule
+}
+
+func newIgnoreMatcher() *ignoreMatcher {
+	return &ignoreMatcher{rules: make(map[string][]ignoreRule)}
+}
+
+// isSkipped reports whether the file or directory is vendored, ignored
+// or located in a vendored or ignored directory. Files outside the
+// working directory are never skipped.
+func (m *ignoreMatcher) isSkipped(file string, isDir bool) bool {
+	file = filepath.ToSlash(filepath.Clean(file))
+	if file == ".." || strings.HasPrefix(file, "../") || path.IsAbs(file) {
+		return false
+	}
+
+	parts := strings.Split(file, "/")
+	for i := range parts {
+		current := strings.Join(parts[:i+1], "/")
+		currentIsDir := isDir || i < len(parts)-1
+		if currentIsDir && slices.Contains(vendoredDirectories, parts[i]) {
+			return true
+		}
+
+		if m.isIgnored(current, currentIsDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIgnored reports whether the last rule matching the path ignores it,
+// rules of nested ignore files take precedence over outer ones
+func (m *ignoreMatcher) isIgnored(file string, isDir bool) bool {
+	ignored := false
+	directory := "."
+	for {
+		for _, rule := range m.load(directory) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+
+			if rule.matches(directory, file) {
+				ignored = !rule.negated
+			}
+		}
+
+		next, _, found := strings.Cut(strings.TrimPrefix(file, directory+"/"), "/")
+		if !found {
+			return ignored
+		}
+
+		if directory == "." {
+			directory = next
+		} else {
+			directory = directory + "/" + next
+		}
+	}
+}
+
+func (r ignoreRule) matches(directory, file string) bool {
+	if directory != "." {
+		file = strings.TrimPrefix(file, directory+"/")
+	}
+
+	return doublestar.MatchUnvalidated(r.pattern, file)
+}
+
+// load returns the rules of all ignore files of the directory
+func (m *ignoreMatcher) load(directory string) []ignoreRule {
+	if rules, exists := m.rules[directory]; exists {
+		return rules
+	}
+
+	rules := make([]ignoreRule, 0)
+	for _, name := range IgnoreFileNames {
+		// missing or unreadable ignore files do not ignore anything
+		if fileRules, err := readIgnoreFile(filepath.Join(filepath.FromSlash(directory), name)); err == nil {
+			rules = append(rules, fileRules...)
+		}
+	}
+	m.rules[directory] = rules
+
+	return rules
+}
+
+// readIgnoreFile reads the rules of a file using the gitignore format
+func readIgnoreFile(file string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := make([]ignoreRule, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		line, rule.negated = strings.CutPrefix(line, "!")
+		line = strings.TrimPrefix(line, "\\")
+		line, rule.dirOnly = strings.CutSuffix(line, "/")
+
+		// patterns containing a slash are relative to the
+		// ignore file, others match at any depth
+		if strings.Contains(line, "/") {
+			rule.pattern = strings.TrimPrefix(line, "/")
+		} else {
+			rule.pattern = "**/" + line
+		}
+
+		if doublestar.ValidatePattern(rule.pattern) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, scanner.Err()
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"slices"
+	"testing"
+)
+
+func TestCollectDiscoveredFiles(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(workingDirectory) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	files := map[string]string{
+		".gitlab-ci.yml":                  "",
+		".gitlab/ci/deploy.yml":           "",
+		".gitlab/ci/generated/build.yml":  "",
+		".gitlab/ci/generated/keep.yml":   "",
+		"service/.gitlab-ci.yml":          "",
+		"service/build/.gitlab-ci.yml":    "",
+		"node_modules/lib/.gitlab-ci.yml": "",
+		"docs/mkdocs.yml":                 "",
+		".gitignore":                      "build/\n.gitlab/ci/generated/*\n!.gitlab/ci/generated/keep.yml\n",
+		".scriptcheckignore":              "deploy.yml\n",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+
+	tests := []struct {
+		patterns []string
+		exclude  []string
+		expected []string
+	}{
+		{nil, nil, []string{".gitlab-ci.yml", ".gitlab/ci/generated/keep.yml", "service/.gitlab-ci.yml"}},
+		{nil, []string{"service/**"}, []string{".gitlab-ci.yml", ".gitlab/ci/generated/keep.yml"}},
+		{[]string{"**/*.yml"}, nil, []string{".gitlab-ci.yml", ".gitlab/ci/generated/keep.yml", "docs/mkdocs.yml", "service/.gitlab-ci.yml"}},
+		{[]string{"node_modules/lib/.gitlab-ci.yml"}, nil, []string{"node_modules/lib/.gitlab-ci.yml"}},
+	}
+
+	for _, test := range tests {
+		options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+		options.Exclude = test.exclude
+
+		collected, err := collectFiles(options, test.patterns)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		for i := range collected {
+			collected[i] = filepath.ToSlash(collected[i])
+		}
+		slices.Sort(collected)
+		if !slices.Equal(collected, test.expected) {
+			t.Errorf("expected files %v for patterns %v, got %v", test.expected, test.patterns, collected)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package runtime
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"slices"
+	"strings"
+)
+
+// textEdit replaces the range between the start and the exclusive end
+// position by the text, where lines and columns start at 1 and columns
+// count characters
+type textEdit struct {
+	line, column       int
+	endLine, endColumn int
+	text               string
+}
+
+func (e textEdit) start() [2]int {
+	return [2]int{e.line, e.column}
+}
+
+func (e textEdit) end() [2]int {
+	return [2]int{e.endLine, e.endColumn}
+}
+
+// overlaps reports whether both edits touch the same range. Ranges
+// only touching at their bounds do not overlap, unless both edits are
+// insertions at the same position as their order would be ambiguous.
+// Identical edits do not overlap, as they get applied once.
+func (e textEdit) overlaps(other textEdit) bool {
+	if e == other {
+		return false
+	}
+
+	if e.start() == e.end() && other.start() == other.end() {
+		return e.start() == other.start()
+	}
+
+	return comparePositions(e.start(), other.end()) < 0 && comparePositions(other.start(), e.end()) < 0
+}
+
+func comparePositions(a, b [2]int) int {
+	return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+}
+
+// applyTextEdits applies the non-overlapping edits to the text
+func applyTextEdits(text string, edits []textEdit) (string, error) {
+	runes := []rune(text)
+
+	lineStarts := []int{0}
+	for i, r := range runes {
+		if r == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	offset := func(line, column int) (int, error) {
+		if line < 1 || line > len(lineStarts) {
+			return 0, fmt.Errorf("line %d out of range", line)
+		}
+
+		lineEnd := len(runes)
+		if line < len(lineStarts) {
+			lineEnd = lineStarts[line] - 1
+		}
+
+		position := lineStarts[line-1] + column - 1
+		if column < 1 || position > lineEnd {
+			return 0, fmt.Errorf("column %d of line %d out of range", column, line)
+		}
+
+		return position, nil
+	}
+
+	sortedEdits := slices.Clone(edits)
+	slices.SortFunc(sortedEdits, func(a, b textEdit) int {
+		return cmp.Or(
+			comparePositions(b.start(), a.start()),
+			comparePositions(b.end(), a.end()),
+			strings.Compare(a.text, b.text),
+		)
+	})
+
+	for _, edit := range slices.Compact(sortedEdits) {
+		start, err := offset(edit.line, edit.column)
+		if err != nil {
+			return "", err
+		}
+
+		end, err := offset(edit.endLine, edit.endColumn)
+		if err != nil {
+			return "", err
+		}
+
+		if end < start {
+			return "", errors.New("edit ends before its start")
+		}
+
+		runes = slices.Concat(runes[:start], []rune(edit.text), runes[end:])
+	}
+
+	return string(runes), nil
+}
+
+// scriptChange contains edits of a script and the corresponding
+// edits of the yaml file the script was read from
+type scriptChange struct {
+	scriptEdits []textEdit
+	yamlEdits   []textEdit
+}
+
+// add maps the edit of the script, excluding its header, to the yaml file.
+// False is returned in case the edit can not be applied to the yaml file.
+func (c *scriptChange) add(script reader.ScriptBlock, edit textEdit) bool {
+	sourceMap := script.SourceMap()
+	start, startExists := sourceMap.Position(edit.line, edit.column)
+	end, endExists := sourceMap.Position(edit.endLine, edit.endColumn)
+	if !startExists || !endExists {
+		return false
+	}
+
+	text, ok := escapeReplacement(sourceMap, edit.text)
+	if !ok {
+		return false
+	}
+
+	c.scriptEdits = append(c.scriptEdits, edit)
+	c.yamlEdits = append(c.yamlEdits, textEdit{start.Line, start.Column, end.Line, end.Column, text})
+	return true
+}
+
+// blockKey identifies a script block within a file, even after
+// the positions of the block changed due to applied changes
+type blockKey struct {
+	name       string
+	occurrence int
+}
+
+func blockKeys(scripts []reader.ScriptBlock) []blockKey {
+	occurrences := make(map[string]int)
+	keys := make([]blockKey, 0, len(scripts))
+	for _, script := range scripts {
+		keys = append(keys, blockKey{script.BlockName, occurrences[script.BlockName]})
+		occurrences[script.BlockName]++
+	}
+
+	return keys
+}
+
+// applyScriptChanges applies the changes of every script to the file content
+// and returns the changed content as well as the indices of the applied changes
+// of every script. Changes get verified by decoding the changed content again,
+// scripts not matching the expected changed script are left unchanged, as well
+// as scripts whose changes break the file.
+func applyScriptChanges(
+	options *Options,
+	decoder reader.ScriptDecoder,
+	file, content string,
+	scripts []reader.ScriptBlock,
+	changes [][]scriptChange,
+) (string, [][]int, error) {
+	keys := blockKeys(scripts)
+	keyIndices := make(map[blockKey]int)
+	for i, key := range keys {
+		keyIndices[key] = i
+	}
+
+	rejected := make([]bool, len(scripts))
+retry:
+	for {
+		yamlEdits := make([]textEdit, 0)
+		scriptYamlEdits := make([][]textEdit, len(scripts))
+		applied := make([][]int, len(scripts))
+		expectedScripts := make([]string, len(scripts))
+		appliedCount := 0
+
+		for i, script := range scripts {
+			expectedScripts[i] = string(script.Script)
+			if rejected[i] {
+				continue
+			}
+
+			scriptEdits := make([]textEdit, 0)
+			for changeIndex, change := range changes[i] {
+				if overlapsAny(change.scriptEdits, scriptEdits) || overlapsAny(change.yamlEdits, yamlEdits) {
+					continue
+				}
+
+				scriptEdits = append(scriptEdits, change.scriptEdits...)
+				yamlEdits = append(yamlEdits, change.yamlEdits...)
+				scriptYamlEdits[i] = append(scriptYamlEdits[i], change.yamlEdits...)
+				applied[i] = append(applied[i], changeIndex)
+				appliedCount++
+			}
+
+			expectedScript, err := applyTextEdits(string(script.Script), scriptEdits)
+			if err != nil {
+				rejected[i] = true
+				continue retry
+			}
+			expectedScripts[i] = expectedScript
+		}
+
+		if appliedCount == 0 {
+			return content, nil, nil
+		}
+
+		changed, err := applyTextEdits(content, yamlEdits)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to apply changes to %s: %w", file, err)
+		}
+
+		changedScripts, err := decoder.DecodeReader(file, bytes.NewReader([]byte(changed)))
+		isBroken := err != nil || len(changedScripts) != len(scripts)
+		isRejected := false
+		changedKeys := blockKeys(changedScripts)
+		for i := 0; i < len(changedKeys) && !isBroken; i++ {
+			index, exists := keyIndices[changedKeys[i]]
+			if exists && string(changedScripts[i].Script) == expectedScripts[index] {
+				continue
+			}
+
+			// scripts without changes must remain unchanged
+			if !exists || len(applied[index]) == 0 {
+				isBroken = true
+				continue
+			}
+
+			rejected[index] = true
+			isRejected = true
+		}
+
+		if isBroken {
+			// changes break the file, thus the changes of a single
+			// script get rejected before applying the remaining ones
+			index := breakingScript(decoder, file, content, scripts, scriptYamlEdits)
+			if index < 0 {
+				return content, nil, nil
+			}
+			options.logger().Printf(
+				"Skipping changes of %s in %s, the changed file can not be decoded",
+				color.Color(scripts[index].BlockName, color.Bold),
+				color.Color(file, color.Bold),
+			)
+			rejected[index] = true
+			continue
+		}
+
+		if !isRejected {
+			return changed, applied, nil
+		}
+	}
+}
+
+// breakingScript returns the index of the first script whose changes break
+// the file or affect other scripts on their own. In case the file only breaks
+// by combining changes, the last script with changes is returned, and -1 in
+// case no script has changes.
+func breakingScript(
+	decoder reader.ScriptDecoder,
+	file, content string,
+	scripts []reader.ScriptBlock,
+	scriptYamlEdits [][]textEdit,
+) int {
+	index := -1
+	for i, yamlEdits := range scriptYamlEdits {
+		if len(yamlEdits) == 0 {
+			continue
+		}
+		index = i
+
+		changed, err := applyTextEdits(content, yamlEdits)
+		if err != nil {
+			return i
+		}
+
+		changedScripts, err := decoder.DecodeReader(file, bytes.NewReader([]byte(changed)))
+		if err != nil || !slices.Equal(blockKeys(changedScripts), blockKeys(scripts)) {
+			return i
+		}
+
+		// the changes must not affect any other script
+		for j, changedScript := range changedScripts {
+			if j != i && changedScript.Script != scripts[j].Script {
+				return i
+			}
+		}
+	}
+
+	return index
+}
+
+// escapeReplacement escapes the replacement according
+// to the style the script is written in the yaml file
+func escapeReplacement(sourceMap *reader.SourceMap, replacement string) (string, bool) {
+	switch sourceMap.Style {
+	case reader.LiteralStyle:
+		// indent all lines besides the first and empty ones
+		lines := strings.Split(replacement, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = strings.Repeat(" ", sourceMap.Indent) + lines[i]
+			}
+		}
+		return strings.Join(lines, "\n"), true
+	case reader.FoldedStyle:
+		return foldReplacement(replacement, sourceMap.Indent), true
+	case reader.DoubleQuotedStyle:
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(replacement), true
+	case reader.SingleQuotedStyle:
+		return strings.ReplaceAll(replacement, "'", "''"), !strings.Contains(replacement, "\n")
+	case reader.PlainStyle:
+		return replacement, !strings.Contains(replacement, "\n")
+	default:
+		return "", false
+	}
+}
+
+// foldReplacement writes the replacement as content of a folded block. Line
+// breaks between lines which are not more indented get folded into a space,
+// thus an empty line is added in between to keep them.
+func foldReplacement(replacement string, indent int) string {
+	isSpaced := func(line string) bool {
+		return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+	}
+
+	builder := new(strings.Builder)
+	previous := ""
+	for i, line := range strings.Split(replacement, "\n") {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		if line == "" {
+			continue
+		}
+
+		if previous != "" && !isSpaced(previous) && !isSpaced(line) {
+			builder.WriteString("\n")
+		}
+		if i > 0 {
+			builder.WriteString(strings.Repeat(" ", indent))
+		}
+		builder.WriteString(line)
+		previous = line
+	}
+
+	return builder.String()
+}
+
+func overlapsAny(edits, others []textEdit) bool {
+	return slices.ContainsFunc(edits, func(edit textEdit) bool {
+		return slices.ContainsFunc(others, edit.overlaps)
+	})
+}
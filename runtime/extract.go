@@ -1,18 +1,17 @@
 package runtime
 
 import (
-	"log"
 	"scriptcheck/color"
 	"scriptcheck/reader"
 )
 
 func ExtractScripts(options *Options, globPatterns []string) error {
-	scripts, files, err := collectAndExtractScripts(options, globPatterns)
+	scripts, files, err := collectAndExtractScripts(options, globPatterns, nil)
 	if err != nil {
 		return err
 	}
 
-	log.Printf(
+	options.logger().Printf(
 		"Extracting %s script(s) from %s file(s)...\n",
 		color.Color(len(scripts), color.Bold),
 		color.Color(len(files), color.Bold),
@@ -22,7 +21,7 @@ func ExtractScripts(options *Options, globPatterns []string) error {
 		return writeErr
 	}
 
-	log.Printf(
+	options.logger().Printf(
 		"Successfully extracted %s scripts and saved into %s directory!",
 		color.Color(len(scripts), color.Bold),
 		color.Color(options.OutputDirectory, color.Bold),
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+	"maps"
+	"path/filepath"
+	"scriptcheck/report"
+	"slices"
+)
+
+// failPolicy decides which findings fail the check by the minimum level
+// of the job or file of a finding. Job thresholds take precedence over file
+// thresholds, which take precedence over the default threshold.
+type failPolicy struct {
+	threshold string
+
+	// thresholds by glob patterns of job and file names
+	jobThresholds  map[string]string
+	fileThresholds map[string]string
+}
+
+func newFailPolicy(options *Options) (*failPolicy, error) {
+	policy := &failPolicy{
+		threshold:      cmp.Or(options.FailOn, report.Levels[0]),
+		jobThresholds:  options.FailOnJobs,
+		fileThresholds: options.FailOnFiles,
+	}
+
+	thresholds := append([]string{policy.threshold}, slices.Collect(maps.Values(policy.jobThresholds))...)
+	thresholds = append(thresholds, slices.Collect(maps.Values(policy.fileThresholds))...)
+	for _, threshold := range thresholds {
+		if !slices.Contains(report.Levels, threshold) {
+			return nil, fmt.Errorf("unknown fail level %s, expected one of %v", threshold, report.Levels)
+		}
+	}
+
+	for pattern := range maps.Keys(policy.jobThresholds) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid job pattern %s", pattern)
+		}
+	}
+
+	for pattern := range maps.Keys(policy.fileThresholds) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid file pattern %s", pattern)
+		}
+	}
+
+	return policy, nil
+}
+
+// thresholdOf returns the minimum level failing for the report, the most
+// specific pattern wins in case multiple patterns match
+func (p *failPolicy) thresholdOf(scriptReport report.ScriptCheckReport) string {
+	if threshold, matches := matchThreshold(p.jobThresholds, scriptReport.Script.Job); matches {
+		return threshold
+	}
+
+	if threshold, matches := matchThreshold(p.fileThresholds, filepath.ToSlash(scriptReport.File)); matches {
+		return threshold
+	}
+
+	return p.threshold
+}
+
+// fails reports whether the report is at or above its threshold, reports
+// of unknown levels and all reports without policy fail
+func (p *failPolicy) fails(scriptReport report.ScriptCheckReport) bool {
+	if p == nil || !slices.Contains(report.Levels, scriptReport.Level) {
+		return true
+	}
+
+	return !report.IsBelowSeverity(scriptReport.Level, p.thresholdOf(scriptReport))
+}
+
+func matchThreshold(thresholds map[string]string, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+
+	// longer patterns are considered more specific
+	patterns := slices.SortedFunc(maps.Keys(thresholds), func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a, b))
+	})
+
+	for _, pattern := range patterns {
+		if doublestar.MatchUnvalidated(pattern, name) {
+			return thresholds[pattern], true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"testing"
+)
+
+func TestFailPolicy(t *testing.T) {
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.FailOn = "warning"
+	options.FailOnJobs = map[string]string{"deploy*": "style", "deploy-docs": "error"}
+	options.FailOnFiles = map[string]string{"tests/**": "error"}
+
+	policy, err := newFailPolicy(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	tests := []struct {
+		file, job, level string
+		fails            bool
+	}{
+		{"ci.yml", "build", "warning", true},
+		{"ci.yml", "build", "info", false},
+		{"ci.yml", "deploy-prod", "style", true},
+		{"ci.yml", "deploy-docs", "warning", false},
+		{"tests/ci.yml", "build", "warning", false},
+		{"tests/ci.yml", "deploy-prod", "info", true},
+		{"ci.yml", "build", "unknown", true},
+	}
+
+	for _, test := range tests {
+		script := exampleScript("echo")
+		script.Job = test.job
+		scriptReport := report.ScriptCheckReport{File: test.file, Level: test.level, Script: script}
+		if fails := policy.fails(scriptReport); fails != test.fails {
+			t.Errorf("expected %s of job %s in %s to fail %t, got %t", test.level, test.job, test.file, test.fails, fails)
+		}
+	}
+
+	options.FailOnJobs = map[string]string{"deploy": "fatal"}
+	if _, err := newFailPolicy(options); err == nil {
+		t.Errorf("expected error for unknown level")
+	}
+}
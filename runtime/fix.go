@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+)
+
+// scriptFixer collects all reports of a run and applies the fixes
+// provided by shellcheck to the yaml files the scripts were read from
+type scriptFixer struct {
+	options *Options
+	decoder reader.ScriptDecoder
+
+	reports []report.ScriptCheckReport
+}
+
+func newScriptFixer(options *Options) (*scriptFixer, error) {
+	decoder, err := newDecoder(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scriptFixer{
+		options: options,
+		decoder: decoder,
+		reports: make([]report.ScriptCheckReport, 0),
+	}, nil
+}
+
+func (f *scriptFixer) collect(reports []report.ScriptCheckReport) error {
+	f.reports = append(f.reports, reports...)
+	return nil
+}
+
+// apply applies the fixes of all collected reports and returns the reports
+// which could not be fixed. In case of a diff the yaml files remain unchanged
+// and the diff of all files is written into the output instead.
+func (f *scriptFixer) apply(scripts []reader.ScriptBlock) ([]report.ScriptCheckReport, error) {
+	fileScripts := make(map[string][]reader.ScriptBlock)
+	files := make([]string, 0)
+	for _, script := range scripts {
+		if _, exists := fileScripts[script.FileName]; !exists {
+			files = append(files, script.FileName)
+		}
+		fileScripts[script.FileName] = append(fileScripts[script.FileName], script)
+	}
+
+	fileReports := make(map[string][]report.ScriptCheckReport)
+	for _, scriptReport := range f.reports {
+		fileReports[scriptReport.File] = append(fileReports[scriptReport.File], scriptReport)
+	}
+
+	diffBuilder := new(strings.Builder)
+	remainingReports := make([]report.ScriptCheckReport, 0)
+	fixCount, fixedFileCount := 0, 0
+	for _, file := range files {
+		if len(fileReports[file]) == 0 {
+			continue
+		}
+
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file to fix: %w", err)
+		}
+
+		fixed, fixedReports, err := f.fixFile(file, string(original), fileScripts[file], fileReports[file])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, scriptReport := range fileReports[file] {
+			if !slices.ContainsFunc(fixedReports, func(fixedReport report.ScriptCheckReport) bool {
+				return isSameReport(fixedReport, scriptReport)
+			}) {
+				remainingReports = append(remainingReports, scriptReport)
+			}
+		}
+
+		if len(fixedReports) == 0 {
+			continue
+		}
+		fixCount += len(fixedReports)
+		fixedFileCount++
+
+		if f.options.Diff {
+			diffBuilder.WriteString(unifiedDiff(file, string(original), fixed))
+		} else if err := os.WriteFile(file, []byte(fixed), 0644); err != nil {
+			return nil, fmt.Errorf("unable to write fixed file: %w", err)
+		}
+	}
+
+	if f.options.Diff {
+		f.options.logger().Printf(
+			"Found %s fixable issue(s) in %s file(s)",
+			color.Color(fixCount, color.Bold),
+			color.Color(fixedFileCount, color.Bold),
+		)
+
+		if diffBuilder.Len() > 0 {
+			writer := NewReportWriter(f.options)
+			if _, err := writer.Write([]byte(diffBuilder.String())); err != nil {
+				_ = writer.Close()
+				return nil, fmt.Errorf("unable to write diff: %w", err)
+			}
+			if err := writer.Close(); err != nil {
+				return nil, fmt.Errorf("unable to write diff: %w", err)
+			}
+		}
+	} else {
+		f.options.logger().Printf(
+			"Fixed %s issue(s) in %s file(s)",
+			color.Color(fixCount, color.Bold),
+			color.Color(fixedFileCount, color.Bold),
+		)
+	}
+
+	return remainingReports, nil
+}
+
+// fixFile applies the fixes of the reports to the file content
+// and returns the fixed content as well as all fixed reports
+func (f *scriptFixer) fixFile(
+	file, content string,
+	scripts []reader.ScriptBlock,
+	reports []report.ScriptCheckReport,
+) (string, []report.ScriptCheckReport, error) {
+	changes := make([][]scriptChange, len(scripts))
+	changeReports := make([][]report.ScriptCheckReport, len(scripts))
+	for _, scriptReport := range reports {
+		index := slices.IndexFunc(scripts, func(script reader.ScriptBlock) bool {
+			return isSameScriptBlock(script, scriptReport.Script)
+		})
+		if index < 0 {
+			continue
+		}
+
+		if change, ok := newFixChange(scriptReport); ok {
+			changes[index] = append(changes[index], change)
+			changeReports[index] = append(changeReports[index], scriptReport)
+		}
+	}
+
+	fixed, applied, err := applyScriptChanges(f.options, f.decoder, file, content, scripts, changes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fixedReports := make([]report.ScriptCheckReport, 0)
+	for i, indices := range applied {
+		for _, index := range indices {
+			fixedReports = append(fixedReports, changeReports[i][index])
+		}
+	}
+
+	return fixed, fixedReports, nil
+}
+
+// newFixChange maps the replacements of the shellcheck
+// fix of the report from the script to the yaml file
+func newFixChange(scriptReport report.ScriptCheckReport) (scriptChange, bool) {
+	script := scriptReport.Script
+	if scriptReport.Report.Fix == nil {
+		return scriptChange{}, false
+	}
+
+	headerLines := script.HeaderLines()
+	change := scriptChange{}
+	for _, replacement := range scriptReport.Report.Fix.Replacements {
+		// fixes of the header are not part of the script
+		if replacement.Line <= headerLines {
+			return scriptChange{}, false
+		}
+
+		edit := textEdit{
+			replacement.Line - headerLines, replacement.Column,
+			replacement.EndLine - headerLines, replacement.EndColumn,
+			replacement.Replacement,
+		}
+		if !change.add(script, edit) {
+			return scriptChange{}, false
+		}
+	}
+
+	return change, len(change.yamlEdits) > 0
+}
+
+func isSameReport(a, b report.ScriptCheckReport) bool {
+	return a.Reason == b.Reason && a.Report == b.Report && isSameScriptBlock(a.Script, b.Script)
+}
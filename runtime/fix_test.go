@@ -0,0 +1,148 @@
+package runtime
+
+import (
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"strings"
+	"testing"
+)
+
+func TestApplyTextEdits(t *testing.T) {
+	cases := []struct {
+		text     string
+		edits    []textEdit
+		expected string
+	}{
+		{
+			text:     "echo $A",
+			edits:    []textEdit{{1, 6, 1, 6, `"`}, {1, 8, 1, 8, `"`}},
+			expected: `echo "$A"`,
+		},
+		{
+			text:     "cd x\necho $A\n",
+			edits:    []textEdit{{2, 6, 2, 8, "${A}"}, {2, 6, 2, 8, "${A}"}},
+			expected: "cd x\necho ${A}\n",
+		},
+		{
+			text:     "ä $A",
+			edits:    []textEdit{{1, 3, 1, 5, "b"}},
+			expected: "ä b",
+		},
+	}
+
+	for _, c := range cases {
+		if result, err := applyTextEdits(c.text, c.edits); err != nil {
+			t.Errorf("%q: unexpected error %s", c.text, err)
+		} else if result != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.text, c.expected, result)
+		}
+	}
+}
+
+func TestFixFile(t *testing.T) {
+	content := strings.Join([]string{
+		"job:",
+		"  script:",
+		"    - echo $PLAIN",
+		"    - 'echo $SINGLE'",
+		"    - \"echo $DOUBLE\"",
+		"    - $UNSAFE",
+		"    - |",
+		"      echo $LITERAL",
+		"",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"job:",
+		"  script:",
+		"    - echo \"$PLAIN\"",
+		"    - 'echo \"$SINGLE\"'",
+		"    - \"echo \\\"$DOUBLE\\\"\"",
+		"    - $UNSAFE",
+		"    - |",
+		"      echo \"$LITERAL\"",
+		"",
+	}, "\n")
+
+	fixer, err := newScriptFixer(newOptionsWithDefaults(reader.PipelineTypeGitlab))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	scripts, err := fixer.decoder.DecodeReader("test.yml", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	// quote the variable of every script
+	reports := make([]report.ScriptCheckReport, 0)
+	for _, script := range scripts {
+		line := script.HeaderLines() + 1
+		column := strings.Index(string(script.Script), "$") + 1
+		endColumn := strings.IndexFunc(string(script.Script)[column:], func(r rune) bool {
+			return r < 'A' || r > 'Z'
+		}) + column + 1
+		if endColumn <= column {
+			endColumn = len(strings.TrimSuffix(string(script.Script), "\n")) + 1
+		}
+
+		reports = append(reports, report.ScriptCheckReport{
+			File:   script.FileName,
+			Reason: "SC2086",
+			Script: script,
+			Report: report.ShellcheckReport{
+				Code: 2086,
+				Fix: &report.ShellcheckFix{Replacements: []report.ShellcheckReplacement{
+					{Line: line, EndLine: line, Column: column, EndColumn: column, Replacement: `"`},
+					{Line: line, EndLine: line, Column: endColumn, EndColumn: endColumn, Replacement: `"`},
+				}},
+			},
+		})
+	}
+
+	fixed, fixedReports, err := fixer.fixFile("test.yml", content, scripts, reports)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if fixed != expected {
+		t.Errorf("expected fixed file\n%s\ngot\n%s", expected, fixed)
+	}
+
+	// quoting the whole plain scalar changes the yaml value
+	if len(fixedReports) != len(reports)-1 {
+		t.Errorf("expected %d fixed reports, got %d", len(reports)-1, len(fixedReports))
+	}
+}
+
+func TestApplyBreakingChanges(t *testing.T) {
+	content := "job:\n  script:\n    - echo a\nother:\n  script:\n    - echo b\n"
+	expected := "job:\n  script:\n    - echo a\nother:\n  script:\n    - echo c\n"
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	decoder, err := newDecoder(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	scripts, err := decoder.DecodeReader("test.yml", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	// the change of the first script turns the file into invalid yaml
+	changes := [][]scriptChange{
+		{{scriptEdits: []textEdit{{1, 7, 1, 7, ": ["}}, yamlEdits: []textEdit{{3, 13, 3, 13, ": ["}}}},
+		{{scriptEdits: []textEdit{{1, 6, 1, 7, "c"}}, yamlEdits: []textEdit{{6, 12, 6, 13, "c"}}}},
+	}
+
+	changed, applied, err := applyScriptChanges(options, decoder, "test.yml", content, scripts, changes)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if changed != expected {
+		t.Errorf("expected changed file\n%s\ngot\n%s", expected, changed)
+	}
+	if len(applied) != 2 || len(applied[0]) != 0 || len(applied[1]) != 1 {
+		t.Errorf("expected the change of the second script to be applied only, got %v", applied)
+	}
+}
@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mvdan.cc/sh/v3/syntax"
+	"os"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatFiles formats the scripts of all files and writes them back into
+// the yaml files. In case of a check or a diff the files remain unchanged
+// and a ScriptCheckError is returned if any script is not formatted.
+func FormatFiles(options *Options, globPatterns []string) error {
+	scripts, files, err := collectAndExtractScripts(options, globPatterns, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	if options.Merge || slices.Contains(files, reader.StdinFile) {
+		return errors.New("unable to format merged files or yaml read from stdin")
+	}
+
+	options.logger().Printf(
+		"Formatting %s script(s) from %s file(s)...\n",
+		color.Color(len(scripts), color.Bold),
+		color.Color(len(files), color.Bold),
+	)
+
+	formatter := newScriptFormatter(options)
+	decoder, err := newDecoder(options)
+	if err != nil {
+		return err
+	}
+
+	fileScripts := make(map[string][]reader.ScriptBlock)
+	for _, script := range scripts {
+		fileScripts[script.FileName] = append(fileScripts[script.FileName], script)
+	}
+
+	diffBuilder := new(strings.Builder)
+	formattedCount, formattedFileCount := 0, 0
+
+	// unformatted scripts which can not be written back using their style
+	unwritable := make([]reader.ScriptBlock, 0)
+	for _, file := range files {
+		if len(fileScripts[file]) == 0 {
+			continue
+		}
+
+		changes := make([][]scriptChange, len(fileScripts[file]))
+		for i, script := range fileScripts[file] {
+			change, isFormatted := formatter.format(script)
+			switch {
+			case isFormatted:
+			case len(change.yamlEdits) == 0:
+				unwritable = append(unwritable, script)
+			default:
+				changes[i] = []scriptChange{change}
+			}
+		}
+
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("unable to read file to format: %w", err)
+		}
+
+		formatted, applied, err := applyScriptChanges(options, decoder, file, string(original), fileScripts[file], changes)
+		if err != nil {
+			return err
+		}
+
+		appliedCount := 0
+		for i, script := range fileScripts[file] {
+			if i < len(applied) && len(applied[i]) > 0 {
+				appliedCount++
+			} else if len(changes[i]) > 0 {
+				// the changed file did not result in the formatted script
+				unwritable = append(unwritable, script)
+			}
+		}
+
+		if appliedCount == 0 {
+			continue
+		}
+		formattedCount += appliedCount
+		formattedFileCount++
+
+		if options.Diff {
+			diffBuilder.WriteString(unifiedDiff(file, string(original), formatted))
+		} else if !options.Check {
+			if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+				return fmt.Errorf("unable to write formatted file: %w", err)
+			}
+		}
+	}
+
+	for _, script := range unwritable {
+		options.logger().Printf(
+			"Unable to write formatted script %s back into %s, the script is left unchanged",
+			script.BlockName,
+			color.Color(script.FileName, color.Bold),
+		)
+	}
+
+	if !options.Check && !options.Diff {
+		options.logger().Printf(
+			"Formatted %s script(s) in %s file(s)",
+			color.Color(formattedCount, color.Bold),
+			color.Color(formattedFileCount, color.Bold),
+		)
+		return nil
+	}
+
+	if diffBuilder.Len() > 0 {
+		writer := NewReportWriter(options)
+		if _, err := writer.Write([]byte(diffBuilder.String())); err != nil {
+			_ = writer.Close()
+			return fmt.Errorf("unable to write diff: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("unable to write diff: %w", err)
+		}
+	}
+
+	if formattedCount+len(unwritable) > 0 {
+		return newScriptCheckError(formattedCount + len(unwritable))
+	}
+
+	return nil
+}
+
+// scriptFormatter pretty prints scripts using the printer of mvdan.cc/sh
+type scriptFormatter struct {
+	debug   bool
+	logger  *log.Logger
+	printer *syntax.Printer
+}
+
+func newScriptFormatter(options *Options) *scriptFormatter {
+	return &scriptFormatter{
+		debug:  options.Debug,
+		logger: options.logger(),
+		printer: syntax.NewPrinter(
+			syntax.Indent(options.Indent),
+			syntax.BinaryNextLine(options.BinaryNextLine),
+			syntax.SwitchCaseIndent(options.CaseIndent),
+		),
+	}
+}
+
+// format returns whether the script is formatted and otherwise the change
+// replacing it by its formatted version, which is empty in case the script
+// can not be written back into the yaml file. Scripts which can not be
+// parsed are considered to be formatted.
+func (f *scriptFormatter) format(script reader.ScriptBlock) (scriptChange, bool) {
+	parser := syntax.NewParser(syntax.KeepComments(true), syntax.Variant(parseLanguages[scriptDialect(script)]))
+	file, err := parser.Parse(strings.NewReader(string(script.Script)), "")
+	if err != nil {
+		if f.debug {
+			f.logger.Printf("Skipping formatting of %s in %s, unable to parse script: %v", script.BlockName, color.Color(script.FileName, color.Bold), err)
+		}
+		return scriptChange{}, true
+	}
+
+	builder := new(strings.Builder)
+	if err := f.printer.Print(builder, file); err != nil {
+		return scriptChange{}, true
+	}
+
+	// trailing line breaks are given by the yaml scalar
+	original := strings.TrimRight(string(script.Script), "\n")
+	formatted := strings.TrimRight(builder.String(), "\n")
+	if original == formatted {
+		return scriptChange{}, true
+	}
+
+	lines := strings.Split(original, "\n")
+	edit := textEdit{1, 1, len(lines), utf8.RuneCountInString(lines[len(lines)-1]) + 1, formatted}
+
+	change := scriptChange{}
+	change.add(script, edit)
+	return change, false
+}
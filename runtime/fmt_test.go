@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"scriptcheck/reader"
+	"strings"
+	"testing"
+)
+
+func TestFormatScripts(t *testing.T) {
+	content := strings.Join([]string{
+		"job:",
+		"  script:",
+		"    - if true;then echo  a;fi",
+		"    - 'echo   b'",
+		"    - |",
+		"      for f in *; do",
+		"      echo \"$f\"",
+		"",
+		"      done",
+		"    - >",
+		"      echo   folded",
+		"    - >",
+		"      for f in *; do",
+		"",
+		"      echo \"$f\"",
+		"",
+		"      done",
+		"    - >-",
+		"      cd   a",
+		"",
+		"      ls",
+		"",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"job:",
+		"  script:",
+		"    - if true; then echo a; fi",
+		"    - 'echo b'",
+		"    - |",
+		"      for f in *; do",
+		"        echo \"$f\"",
+		"",
+		"      done",
+		"    - >",
+		"      echo folded",
+		"    - >",
+		"      for f in *; do",
+		"        echo \"$f\"",
+		"      done",
+		"    - >-",
+		"      cd a",
+		"",
+		"      ls",
+		"",
+	}, "\n")
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Indent = 2
+
+	decoder, err := newDecoder(options)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	scripts, err := decoder.DecodeReader("test.yml", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	formatter := newScriptFormatter(options)
+	changes := make([][]scriptChange, len(scripts))
+	for i, script := range scripts {
+		if change, isFormatted := formatter.format(script); !isFormatted {
+			changes[i] = []scriptChange{change}
+		}
+	}
+
+	formatted, _, err := applyScriptChanges(options, decoder, "test.yml", content, scripts, changes)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if formatted != expected {
+		t.Errorf("expected formatted file\n%s\ngot\n%s", expected, formatted)
+	}
+}
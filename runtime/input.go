@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+)
+
+// Input is a yaml file given by its name and optionally its content
+type Input struct {
+	Name string
+	// content of the file, which gets read in case no content is given
+	Content []byte
+}
+
+// DecodeInputs decodes the scripts of all inputs and applies the overrides,
+// neither stdin nor any other file besides the inputs and preludes is read
+func DecodeInputs(ctx context.Context, options *Options, inputs []Input) ([]reader.ScriptBlock, error) {
+	decoder, err := reader.NewDecoder(options.PipelineType, options.Debug, options.DefaultShell)
+	if err != nil {
+		return nil, err
+	}
+	decoder = decoder.WithPreludes(options.Preludes).WithLogger(options.logger())
+
+	scripts := make([]reader.ScriptBlock, 0)
+	for _, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var inputScripts []reader.ScriptBlock
+		if input.Content == nil {
+			inputScripts, err = decoder.DecodeFile(input.Name)
+		} else {
+			inputScripts, err = decoder.DecodeReader(input.Name, bytes.NewReader(input.Content))
+		}
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, inputScripts...)
+	}
+	applyOverrides(options.Overrides, scripts)
+
+	return scripts, nil
+}
+
+// CheckScripts checks the scripts and returns the sorted reports of all
+// checkers without printing them, checking stops once the context is done
+func CheckScripts(ctx context.Context, options *Options, scripts []reader.ScriptBlock) ([]report.ScriptCheckReport, error) {
+	reports := make([]report.ScriptCheckReport, 0)
+	if len(scripts) == 0 {
+		return reports, nil
+	}
+
+	err := runCheckers(ctx, options, scripts, func(scriptReports []report.ScriptCheckReport) error {
+		reports = append(reports, scriptReports...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.SortReports(reports)
+
+	return reports, nil
+}
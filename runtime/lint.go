@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"scriptcheck/color"
+	"scriptcheck/report"
+)
+
+func LintDirectives(options *Options, globPatterns []string) error {
+	files, err := collectFiles(options, globPatterns)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 && options.Strict {
+		return errors.New("no files found")
+	}
+
+	options.logger().Printf("Linting directives of %s file(s)...\n", color.Color(len(files), color.Bold))
+
+	decoder, err := newDecoder(options)
+	if err != nil {
+		return err
+	}
+
+	printer := newReportPrinter(options)
+	for _, file := range files {
+		problems, err := decoder.LintFile(file, options.RequireSuppressionReason)
+		if err != nil {
+			options.logger().Printf("Error while linting: %s\n", err.Error())
+			return fmt.Errorf("unable to lint file: %w", err)
+		}
+
+		if err := printer.print(report.NewDirectiveReports(problems)); err != nil {
+			return err
+		}
+	}
+
+	return printer.close()
+}
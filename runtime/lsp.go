@@ -0,0 +1,561 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// ServeLanguageServer runs a language server reading messages from the input
+// and writing messages to the output until the client exits. Diagnostics are
+// published for all open pipeline files using the content of the editor.
+func ServeLanguageServer(options *Options, input io.Reader, output io.Writer) error {
+	server, err := newLanguageServer(options, newLspConnection(input, output))
+	if err != nil {
+		return err
+	}
+
+	go server.checkDocuments()
+	defer close(server.checkSignal)
+
+	return server.serve()
+}
+
+// languageDocument is a pipeline file opened by the client
+type languageDocument struct {
+	uri  string
+	file string
+
+	version int
+	text    string
+
+	// content, scripts and reports of the last check
+	checkedText string
+	scripts     []reader.ScriptBlock
+	reports     []report.ScriptCheckReport
+}
+
+type languageServer struct {
+	options *Options
+	conn    *lspConnection
+	decoder reader.ScriptDecoder
+
+	initialized bool
+	shutdown    bool
+
+	lock      sync.Mutex
+	documents map[string]*languageDocument
+	// documents changed since they were checked
+	pending     map[string]bool
+	checkSignal chan struct{}
+}
+
+func newLanguageServer(options *Options, conn *lspConnection) (*languageServer, error) {
+	decoder, err := reader.NewDecoder(options.PipelineType, options.Debug, options.DefaultShell)
+	if err != nil {
+		return nil, err
+	}
+
+	return &languageServer{
+		options:   options,
+		conn:      conn,
+		decoder:   decoder.WithPreludes(options.Preludes).WithLogger(options.logger()),
+		documents: make(map[string]*languageDocument),
+		pending:   make(map[string]bool),
+		// a single signal covers any number of changes
+		checkSignal: make(chan struct{}, 1),
+	}, nil
+}
+
+// serve handles all messages until the client exits or closes the input
+func (s *languageServer) serve() error {
+	for {
+		message, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("language client exited without shutdown")
+			}
+			return nil
+		}
+
+		result, lspErr := s.handle(message)
+		if message.Id == nil {
+			continue
+		}
+
+		if err := s.conn.reply(message.Id, result, lspErr); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or notification and returns the result of requests
+func (s *languageServer) handle(message *lspMessage) (any, *lspError) {
+	if !s.initialized && message.Method != "initialize" {
+		return nil, &lspError{lspServerNotInitialized, "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &lspError{lspInvalidRequest, "server is shut down"}
+	}
+
+	var err error
+	switch message.Method {
+	case "initialize":
+		s.initialized = true
+		return s.capabilities(), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params lspDidOpenParams
+		if err = json.Unmarshal(message.Params, &params); err == nil {
+			s.update(params.TextDocument.Uri, params.TextDocument.Version, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params lspDidChangeParams
+		if err = json.Unmarshal(message.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.update(params.TextDocument.Uri, params.TextDocument.Version, text)
+		}
+	case "textDocument/didClose":
+		var params lspDidCloseParams
+		if err = json.Unmarshal(message.Params, &params); err == nil {
+			s.close(params.TextDocument.Uri)
+		}
+	case "textDocument/codeAction":
+		var params lspCodeActionParams
+		if err = json.Unmarshal(message.Params, &params); err == nil {
+			return s.codeActions(params), nil
+		}
+	case "textDocument/hover":
+		var params lspPositionParams
+		if err = json.Unmarshal(message.Params, &params); err == nil {
+			return s.hover(params), nil
+		}
+	default:
+		if message.Id != nil {
+			return nil, &lspError{lspMethodNotFound, fmt.Sprintf("method %s not supported", message.Method)}
+		}
+	}
+
+	if err != nil {
+		return nil, &lspError{lspInvalidParams, err.Error()}
+	}
+
+	return nil, nil
+}
+
+func (s *languageServer) capabilities() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			// documents are synchronized in full
+			"textDocumentSync": map[string]any{"openClose": true, "change": 1},
+			"codeActionProvider": map[string]any{
+				"codeActionKinds": []string{"quickfix"},
+			},
+			"hoverProvider": true,
+		},
+		"serverInfo": map[string]any{"name": "scriptcheck"},
+	}
+}
+
+// update stores the content of the document and schedules its
+// check, documents other than pipeline files are not checked
+func (s *languageServer) update(uri string, version int, text string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	document, exists := s.documents[uri]
+	if !exists {
+		file, ok := s.pipelineFile(uri)
+		if !ok {
+			return
+		}
+		document = &languageDocument{uri: uri, file: file}
+		s.documents[uri] = document
+	}
+
+	document.version, document.text = version, text
+	s.pending[uri] = true
+
+	select {
+	case s.checkSignal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *languageServer) close(uri string) {
+	s.lock.Lock()
+	_, exists := s.documents[uri]
+	delete(s.documents, uri)
+	delete(s.pending, uri)
+	s.lock.Unlock()
+
+	if exists {
+		// diagnostics of closed documents get removed
+		s.publish(uri, 0, []lspDiagnostic{})
+	}
+}
+
+// pipelineFile returns the name of the file of the uri relative to the
+// working directory, in case it is a pipeline file which is not excluded
+func (s *languageServer) pipelineFile(uri string) (string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return "", false
+	}
+
+	file := filepath.FromSlash(parsed.Path)
+	// paths of windows drives start with a slash
+	if filepath.VolumeName(strings.TrimPrefix(file, `\`)) != "" {
+		file = strings.TrimPrefix(file, `\`)
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		if relative, err := filepath.Rel(workingDir, file); err == nil && !strings.HasPrefix(relative, "..") {
+			file = relative
+		}
+	}
+
+	slashFile := filepath.ToSlash(file)
+	patterns := slices.Concat(reader.PipelineFilePatterns(s.options.PipelineType), s.options.Include)
+	isPipelineFile := slices.ContainsFunc(patterns, func(pattern string) bool {
+		return doublestar.MatchUnvalidated(pattern, slashFile)
+	})
+
+	return file, isPipelineFile && !isExcluded(s.options.Exclude, file)
+}
+
+// checkDocuments checks all pending documents whenever signaled and
+// publishes their diagnostics, unless they changed during the check
+func (s *languageServer) checkDocuments() {
+	for range s.checkSignal {
+		s.lock.Lock()
+		documents := make([]languageDocument, 0, len(s.pending))
+		for uri := range s.pending {
+			documents = append(documents, *s.documents[uri])
+		}
+		clear(s.pending)
+		s.lock.Unlock()
+
+		for _, document := range documents {
+			scripts, reports, err := s.check(document.file, document.text)
+
+			s.lock.Lock()
+			current, exists := s.documents[document.uri]
+			isCurrent := exists && current.version == document.version && current.text == document.text
+			if isCurrent {
+				current.checkedText, current.scripts, current.reports = document.text, scripts, reports
+			}
+			s.lock.Unlock()
+
+			if !isCurrent {
+				continue
+			}
+
+			diagnostics := make([]lspDiagnostic, 0, len(reports))
+			lines := strings.Split(document.text, "\n")
+			var decodeErr *lspDecodeError
+			if errors.As(err, &decodeErr) {
+				diagnostics = append(diagnostics, lspDiagnostic{
+					Severity: lspSeverityError,
+					Source:   "scriptcheck",
+					Message:  decodeErr.Error(),
+				})
+			} else if err != nil {
+				s.options.logger().Printf("Unable to check %s: %v", color.Color(document.file, color.Bold), err)
+				_ = s.conn.notify("window/showMessage", map[string]any{
+					"type":    1,
+					"message": fmt.Sprintf("scriptcheck: unable to check %s: %v", document.file, err),
+				})
+			}
+
+			for _, scriptReport := range reports {
+				diagnostics = append(diagnostics, newLspDiagnostic(lines, scriptReport))
+			}
+
+			s.publish(document.uri, document.version, diagnostics)
+		}
+	}
+}
+
+// lspDecodeError describes a pipeline file which can not be decoded
+type lspDecodeError struct {
+	err error
+}
+
+func (e *lspDecodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *lspDecodeError) Unwrap() error {
+	return e.err
+}
+
+// check decodes the scripts of the content and checks them
+func (s *languageServer) check(file, text string) ([]reader.ScriptBlock, []report.ScriptCheckReport, error) {
+	scripts, err := s.decoder.DecodeReader(file, strings.NewReader(text))
+	if err != nil {
+		return nil, nil, &lspDecodeError{err}
+	}
+	applyOverrides(s.options.Overrides, scripts)
+
+	reports := make([]report.ScriptCheckReport, 0)
+	if len(scripts) == 0 {
+		return scripts, reports, nil
+	}
+
+	err = runCheckers(context.Background(), s.options, scripts, func(scriptReports []report.ScriptCheckReport) error {
+		reports = append(reports, scriptReports...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return scripts, reports, nil
+}
+
+func (s *languageServer) publish(uri string, version int, diagnostics []lspDiagnostic) {
+	params := lspPublishDiagnosticsParams{Uri: uri, Version: version, Diagnostics: diagnostics}
+	if err := s.conn.notify("textDocument/publishDiagnostics", params); err != nil {
+		s.options.logger().Printf("Unable to publish diagnostics: %v", err)
+	}
+}
+
+// checkedDocument returns a copy of the document, in case the
+// reports of its last check belong to its current content
+func (s *languageServer) checkedDocument(uri string) (languageDocument, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	document, exists := s.documents[uri]
+	if !exists || document.checkedText != document.text {
+		return languageDocument{}, false
+	}
+
+	return *document, true
+}
+
+// codeActions returns the fixes provided by shellcheck and the directives
+// disabling the rule for all reports overlapping the requested range
+func (s *languageServer) codeActions(params lspCodeActionParams) []lspCodeAction {
+	actions := make([]lspCodeAction, 0)
+	document, ok := s.checkedDocument(params.TextDocument.Uri)
+	if !ok {
+		return actions
+	}
+
+	lines := strings.Split(document.text, "\n")
+	for _, scriptReport := range document.reports {
+		diagnostic := newLspDiagnostic(lines, scriptReport)
+		if !rangesOverlap(diagnostic.Range, params.Range) {
+			continue
+		}
+
+		if edits, ok := s.fixEdits(document, scriptReport); ok {
+			actions = append(actions, lspCodeAction{
+				Title:       fmt.Sprintf("Fix %s: %s", scriptReport.Reason, scriptReport.Message),
+				Kind:        "quickfix",
+				Diagnostics: []lspDiagnostic{diagnostic},
+				IsPreferred: true,
+				Edit:        lspWorkspaceEdit{Changes: map[string][]lspTextEdit{document.uri: edits}},
+			})
+		}
+
+		// only rules of shellcheck can be disabled by a directive
+		if strings.HasPrefix(scriptReport.Reason, "SC") {
+			actions = append(actions, lspCodeAction{
+				Title:       fmt.Sprintf("Disable %s for this script", scriptReport.Reason),
+				Kind:        "quickfix",
+				Diagnostics: []lspDiagnostic{diagnostic},
+				Edit: lspWorkspaceEdit{Changes: map[string][]lspTextEdit{
+					document.uri: {disableDirectiveEdit(lines, scriptReport)},
+				}},
+			})
+		}
+	}
+
+	return actions
+}
+
+// fixEdits returns the edits applying the fix of the report, in case
+// the fix can be applied without breaking the pipeline file
+func (s *languageServer) fixEdits(document languageDocument, scriptReport report.ScriptCheckReport) ([]lspTextEdit, bool) {
+	change, ok := newFixChange(scriptReport)
+	if !ok {
+		return nil, false
+	}
+
+	changes := make([][]scriptChange, len(document.scripts))
+	for i, script := range document.scripts {
+		if isSameScriptBlock(script, scriptReport.Script) {
+			changes[i] = []scriptChange{change}
+		}
+	}
+
+	_, applied, err := applyScriptChanges(s.options, s.decoder, document.file, document.text, document.scripts, changes)
+	if err != nil || !slices.ContainsFunc(applied, func(indices []int) bool { return len(indices) > 0 }) {
+		return nil, false
+	}
+
+	lines := strings.Split(document.text, "\n")
+	edits := make([]lspTextEdit, 0, len(change.yamlEdits))
+	for _, edit := range change.yamlEdits {
+		edits = append(edits, lspTextEdit{
+			Range: lspRange{
+				Start: newLspPosition(lines, edit.line, edit.column),
+				End:   newLspPosition(lines, edit.endLine, edit.endColumn),
+			},
+			NewText: edit.text,
+		})
+	}
+
+	return edits, true
+}
+
+// disableDirectiveEdit returns the edit disabling the rule of the report for
+// its script, either by extending the directive above the script or by
+// inserting a directive comment using the indentation of the script
+func disableDirectiveEdit(lines []string, scriptReport report.ScriptCheckReport) lspTextEdit {
+	line := scriptReport.Script.DirectiveLine()
+	text := lines[min(max(line, 1), len(lines))-1]
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+
+	if line > 1 {
+		previous := strings.TrimRight(lines[line-2], " \t\r")
+		if reader.IsScriptCheckComment(previous) && strings.HasPrefix(previous, indent+"#") {
+			end := newLspPosition(lines, line-1, len([]rune(previous))+1)
+			return lspTextEdit{Range: lspRange{end, end}, NewText: " disable=" + scriptReport.Reason}
+		}
+	}
+
+	start := newLspPosition(lines, line, 1)
+	return lspTextEdit{
+		Range:   lspRange{start, start},
+		NewText: fmt.Sprintf("%s# scriptcheck disable=%s\n", indent, scriptReport.Reason),
+	}
+}
+
+// hover returns the messages of all reports at the position
+func (s *languageServer) hover(params lspPositionParams) *lspHover {
+	document, ok := s.checkedDocument(params.TextDocument.Uri)
+	if !ok {
+		return nil
+	}
+
+	lines := strings.Split(document.text, "\n")
+	var hover *lspHover
+	messages := make([]string, 0)
+	for _, scriptReport := range document.reports {
+		reportRange := newLspDiagnostic(lines, scriptReport).Range
+		if !rangesOverlap(reportRange, lspRange{params.Position, params.Position}) {
+			continue
+		}
+
+		message := fmt.Sprintf("**%s** (%s): %s", scriptReport.Reason, scriptReport.Level, scriptReport.Message)
+		// only shellcheck reports provide further information
+		if strings.HasPrefix(scriptReport.Reason, "SC") {
+			message += fmt.Sprintf("\n\nhttps://www.shellcheck.net/wiki/%s", scriptReport.Reason)
+		}
+		messages = append(messages, message)
+
+		if hover == nil {
+			hover = &lspHover{Range: reportRange}
+		}
+	}
+
+	if hover != nil {
+		hover.Contents.Kind = "markdown"
+		hover.Contents.Value = strings.Join(messages, "\n\n---\n\n")
+	}
+
+	return hover
+}
+
+func newLspDiagnostic(lines []string, scriptReport report.ScriptCheckReport) lspDiagnostic {
+	endLine, endColumn := scriptReport.EndLine, scriptReport.EndColumn
+	if endLine < scriptReport.Line {
+		endLine, endColumn = scriptReport.Line, scriptReport.Column
+	}
+
+	diagnostic := lspDiagnostic{
+		Range: lspRange{
+			Start: newLspPosition(lines, scriptReport.Line, scriptReport.Column),
+			End:   newLspPosition(lines, endLine, endColumn),
+		},
+		Severity: lspSeverity(scriptReport.Level),
+		Code:     scriptReport.Reason,
+		Source:   "scriptcheck",
+		Message:  scriptReport.Message,
+	}
+
+	if strings.HasPrefix(scriptReport.Reason, "SC") {
+		diagnostic.CodeDescription = &struct {
+			Href string `json:"href"`
+		}{fmt.Sprintf("https://www.shellcheck.net/wiki/%s", scriptReport.Reason)}
+	}
+
+	return diagnostic
+}
+
+func lspSeverity(level string) int {
+	switch level {
+	case "error":
+		return lspSeverityError
+	case "warning":
+		return lspSeverityWarning
+	case "info":
+		return lspSeverityInformation
+	default:
+		return lspSeverityHint
+	}
+}
+
+// newLspPosition converts the position of the yaml file, where lines and
+// columns start at 1 and columns count characters, into the position of
+// the protocol, where both start at 0 and columns count UTF-16 code units.
+// Positions outside the lines are moved to the closest position inside.
+func newLspPosition(lines []string, line, column int) lspPosition {
+	if line < 1 {
+		return lspPosition{}
+	}
+	if line > len(lines) {
+		line, column = len(lines), len([]rune(lines[len(lines)-1]))+1
+	}
+
+	runes := []rune(lines[line-1])
+	column = min(max(column, 1), len(runes)+1)
+
+	return lspPosition{Line: line - 1, Character: len(utf16.Encode(runes[:column-1]))}
+}
+
+// rangesOverlap reports whether both ranges share a position,
+// where ranges touching at their bounds overlap
+func rangesOverlap(a, b lspRange) bool {
+	return comparePositions(lspPositionPair(a.Start), lspPositionPair(b.End)) <= 0 &&
+		comparePositions(lspPositionPair(b.Start), lspPositionPair(a.End)) <= 0
+}
+
+func lspPositionPair(position lspPosition) [2]int {
+	return [2]int{position.Line, position.Character}
+}
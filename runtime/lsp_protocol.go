@@ -0,0 +1,218 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// error codes defined by JSON-RPC and the language server protocol
+const (
+	lspMethodNotFound       = -32601
+	lspInvalidParams        = -32602
+	lspServerNotInitialized = -32002
+	lspInvalidRequest       = -32600
+)
+
+// severities of diagnostics
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+	lspSeverityHint        = 4
+)
+
+// lspMessage is a JSON-RPC request, notification or response,
+// notifications are requests without id
+type lspMessage struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspConnection reads and writes messages framed by a
+// Content-Length header, writing is safe for concurrent use
+type lspConnection struct {
+	reader *textproto.Reader
+
+	writeLock sync.Mutex
+	writer    io.Writer
+}
+
+func newLspConnection(input io.Reader, output io.Writer) *lspConnection {
+	return &lspConnection{
+		reader: textproto.NewReader(bufio.NewReader(input)),
+		writer: output,
+	}
+}
+
+// read reads the next message, io.EOF is returned once the input is closed
+func (c *lspConnection) read() (*lspMessage, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("unable to read message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid content length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, content); err != nil {
+		return nil, fmt.Errorf("unable to read message content: %w", err)
+	}
+
+	message := new(lspMessage)
+	if err := json.Unmarshal(content, message); err != nil {
+		return nil, fmt.Errorf("unable to parse message: %w", err)
+	}
+
+	return message, nil
+}
+
+func (c *lspConnection) write(message lspMessage) error {
+	message.JsonRpc = "2.0"
+	content, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("unable to encode message: %w", err)
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		return fmt.Errorf("unable to write message: %w", err)
+	}
+
+	return nil
+}
+
+func (c *lspConnection) notify(method string, params any) error {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("unable to encode message: %w", err)
+	}
+
+	return c.write(lspMessage{Method: method, Params: content})
+}
+
+func (c *lspConnection) reply(id json.RawMessage, result any, err *lspError) error {
+	// successful responses require a result, even if it is null
+	if err == nil && result == nil {
+		result = json.RawMessage("null")
+	}
+
+	return c.write(lspMessage{Id: id, Result: result, Error: err})
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentIdentifier struct {
+	Uri string `json:"uri"`
+}
+
+type lspTextDocumentItem struct {
+	Uri     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument lspTextDocumentItem `json:"textDocument"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument struct {
+		Uri     string `json:"uri"`
+		Version int    `json:"version"`
+	} `json:"textDocument"`
+	// documents are synchronized in full, thus the
+	// last change contains the whole document
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspDidCloseParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+}
+
+type lspCodeActionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Range        lspRange                  `json:"range"`
+	Context      struct {
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	} `json:"context"`
+}
+
+type lspDiagnostic struct {
+	Range           lspRange `json:"range"`
+	Severity        int      `json:"severity"`
+	Code            string   `json:"code"`
+	CodeDescription *struct {
+		Href string `json:"href"`
+	} `json:"codeDescription,omitempty"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+type lspPublishDiagnosticsParams struct {
+	Uri         string          `json:"uri"`
+	Version     int             `json:"version"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspCodeAction struct {
+	Title       string           `json:"title"`
+	Kind        string           `json:"kind"`
+	Diagnostics []lspDiagnostic  `json:"diagnostics,omitempty"`
+	IsPreferred bool             `json:"isPreferred,omitempty"`
+	Edit        lspWorkspaceEdit `json:"edit"`
+}
+
+type lspHover struct {
+	Contents struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	} `json:"contents"`
+	Range lspRange `json:"range"`
+}
@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLanguageServer(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(workingDirectory) })
+	directory := t.TempDir()
+	if err := os.Chdir(directory); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Checker = CheckerParse
+
+	clientInput, serverOutput := io.Pipe()
+	serverInput, clientOutput := io.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- ServeLanguageServer(options, serverInput, serverOutput) }()
+
+	client := newLspConnection(clientInput, clientOutput)
+	send := func(id int, method string, params any) {
+		content, err := json.Marshal(params)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		message := lspMessage{Method: method, Params: content}
+		if id > 0 {
+			message.Id = json.RawMessage(strconv.Itoa(id))
+		}
+		if err := client.write(message); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+	receive := func(result any) {
+		message, err := client.read()
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		content := message.Params
+		if message.Method == "" {
+			content, _ = json.Marshal(message.Result)
+		}
+		if err := json.Unmarshal(content, result); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+
+	send(1, "initialize", map[string]any{})
+	receive(&map[string]any{})
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(directory, ".gitlab-ci.yml"))
+	send(0, "textDocument/didOpen", lspDidOpenParams{
+		TextDocument: lspTextDocumentItem{Uri: uri, Version: 1, Text: "job:\n  script: echo 😀 'a\n"},
+	})
+
+	var published lspPublishDiagnosticsParams
+	receive(&published)
+	if len(published.Diagnostics) != 1 || published.Diagnostics[0].Code != SyntaxErrorReason {
+		t.Fatalf("expected a syntax error, got %v", published.Diagnostics)
+	}
+	if start := published.Diagnostics[0].Range.Start; start.Line != 1 {
+		t.Errorf("expected diagnostic on line 1, got %v", start)
+	}
+
+	send(2, "textDocument/hover", lspPositionParams{
+		TextDocument: lspTextDocumentIdentifier{Uri: uri},
+		Position:     published.Diagnostics[0].Range.Start,
+	})
+	var hover lspHover
+	receive(&hover)
+	if !strings.Contains(hover.Contents.Value, published.Diagnostics[0].Message) {
+		t.Errorf("expected hover to show the message, got %q", hover.Contents.Value)
+	}
+
+	send(3, "shutdown", nil)
+	receive(new(any))
+	send(0, "exit", nil)
+	if err := <-served; err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestNewLspPosition(t *testing.T) {
+	lines := []string{"a: b", "  - echo 😀 ä x", ""}
+	tests := []struct {
+		line, column int
+		expected     lspPosition
+	}{
+		{1, 1, lspPosition{0, 0}},
+		{2, 10, lspPosition{1, 9}},
+		// characters outside the basic plane take two code units
+		{2, 11, lspPosition{1, 11}},
+		{2, 14, lspPosition{1, 14}},
+		{2, 100, lspPosition{1, 15}},
+		{0, 5, lspPosition{0, 0}},
+		{5, 1, lspPosition{2, 0}},
+	}
+
+	for _, test := range tests {
+		if position := newLspPosition(lines, test.line, test.column); position != test.expected {
+			t.Errorf("%d:%d: expected %v, got %v", test.line, test.column, test.expected, position)
+		}
+	}
+}
+
+func TestDisableDirectiveEdit(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected lspTextEdit
+	}{
+		{
+			"job:\n  script:\n    - echo\n    - echo $A\n",
+			lspTextEdit{lspRange{lspPosition{3, 0}, lspPosition{3, 0}}, "    # scriptcheck disable=SC2086\n"},
+		},
+		{
+			"job:\n  script: |\n    echo $A\n",
+			lspTextEdit{lspRange{lspPosition{1, 0}, lspPosition{1, 0}}, "  # scriptcheck disable=SC2086\n"},
+		},
+		{
+			"job:\n  # scriptcheck shell=bash\n  script: &anchor\n    echo $A\n",
+			lspTextEdit{lspRange{lspPosition{1, 26}, lspPosition{1, 26}}, " disable=SC2086"},
+		},
+	}
+
+	decoder, err := reader.NewDecoder(reader.PipelineTypeGitlab, false, "")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	for _, test := range tests {
+		scripts, err := decoder.DecodeReader("test.yml", strings.NewReader(test.yaml))
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		scriptReport := report.ScriptCheckReport{Reason: "SC2086", Script: scripts[len(scripts)-1]}
+		edit := disableDirectiveEdit(strings.Split(test.yaml, "\n"), scriptReport)
+		if edit != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.yaml, test.expected, edit)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"log"
 	"scriptcheck/format"
 	"scriptcheck/reader"
 )
@@ -16,12 +17,102 @@ type Options struct {
 	Debug        bool
 	Merge        bool
 
-	Strict              bool
-	DefaultShell        string
-	ExperimentalFolding bool
+	Strict       bool
+	DefaultShell string
+
+	// configuration file, which is searched unless given or disabled
+	ConfigFile string
+	NoConfig   bool
+
+	// patterns of files checked when no pattern is given
+	// and patterns of files excluded from checking
+	Include []string
+	Exclude []string
+
+	// shell, disabled rules and severity of scripts by file and job
+	Overrides []Override
+
+	// virtual file name of the yaml read from stdin
+	StdinFileName string
+
+	// references of shared scripts prepended to every script
+	Preludes []string
 
 	ShellCheckArgs []string
 	Format         format.Format
 
+	// checker used for all scripts and checkers
+	// overriding it for specific shell dialects
+	Checker         CheckerType
+	DialectCheckers map[string]string
+
+	// number of concurrently running shellcheck processes
+	Jobs int
+
+	// directory to cache shellcheck results in, the
+	// cache is disabled when no directory is given
+	CacheDir string
+	NoCache  bool
+	// maximum size of the cache in megabytes
+	CacheMaxSize int
+
+	// only report findings on lines changed since the git reference,
+	// or all findings of changed scripts in case of changed blocks
+	ChangedSince  string
+	ChangedBlocks bool
+
+	// keep checking files whenever they change
+	Watch bool
+
+	// apply fixes provided by shellcheck to the yaml files
+	Fix bool
+	// print the diff of all fixes instead of applying them
+	Diff bool
+
+	// indentation of formatted scripts in spaces, zero indents using tabs
+	Indent uint
+	// place binary operators at the start of continued lines
+	BinaryNextLine bool
+	// indent the patterns of case statements
+	CaseIndent bool
+	// only report scripts which are not formatted
+	Check bool
+
+	// profile of rule levels, either the name of a built-in or
+	// custom profile or a profile file, and levels of single rules
+	Profile    string
+	RuleLevels map[string]string
+	// custom profiles selectable by their name
+	Profiles map[string]Profile
+
+	// minimum level of findings failing the check and the levels
+	// overriding it for jobs and files matching a glob pattern
+	FailOn      string
+	FailOnJobs  map[string]string
+	FailOnFiles map[string]string
+
+	// baseline of accepted findings, which are not reported
+	Baseline string
+	// write all findings into the baseline instead of reporting them
+	UpdateBaseline bool
+
+	// report suppressions without justification
+	RequireSuppressionReason bool
+	// report suppressions no longer suppressing any finding
+	UnusedSuppressions bool
+
 	OutputDirectory string
+
+	// logger of all progress and debug messages, the standard logger is used
+	// unless given, thus embedding applications can discard all messages
+	Logger *log.Logger
+}
+
+// logger returns the configured logger or the standard logger
+func (o *Options) logger() *log.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+
+	return log.Default()
 }
@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"scriptcheck/format"
+	"scriptcheck/report"
+)
+
+// reportPrinter streams reports into the configured output while they
+// get found. The formatter only gets started with the first report,
+// thus runs without any findings do not print anything.
+type reportPrinter struct {
+	options   *Options
+	writer    io.WriteCloser
+	formatter format.ShellCheckReportFormatter
+
+	// policy deciding which reports fail, all reports fail without policy
+	policy *failPolicy
+
+	reportCount int
+	failCount   int
+	levelCounts map[string]int
+}
+
+func newReportPrinter(options *Options) *reportPrinter {
+	return &reportPrinter{
+		options:     options,
+		levelCounts: make(map[string]int),
+	}
+}
+
+// print formats the reports and writes them into the output
+func (p *reportPrinter) print(reports []report.ScriptCheckReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	if p.formatter == nil {
+		formatter, err := format.NewFormatter(p.options.Format)
+		if err != nil {
+			return err
+		}
+
+		p.writer = NewReportWriter(p.options)
+		p.formatter = formatter
+		if err := p.formatter.Begin(p.writer); err != nil {
+			return fmt.Errorf("unable to write shellcheck output: %w", err)
+		}
+	}
+
+	for _, scriptReport := range reports {
+		if err := p.formatter.Report(p.writer, scriptReport); err != nil {
+			return fmt.Errorf("unable to write shellcheck output: %w", err)
+		}
+
+		p.levelCounts[scriptReport.Level]++
+		if p.policy.fails(scriptReport) {
+			p.failCount++
+		}
+	}
+	p.reportCount += len(reports)
+
+	return nil
+}
+
+// close finishes the output and returns a ScriptCheckError
+// in case any report got printed
+func (p *reportPrinter) close() error {
+	if p.formatter == nil {
+		return nil
+	}
+
+	if err := p.formatter.End(p.writer); err != nil {
+		_ = p.writer.Close()
+		return fmt.Errorf("unable to write shellcheck output: %w", err)
+	}
+
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("unable to write shellcheck output: %w", err)
+	}
+
+	return &ScriptCheckError{p.reportCount, p.failCount, p.levelCounts}
+}
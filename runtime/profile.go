@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"maps"
+	"os"
+	"path/filepath"
+	"scriptcheck/report"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Profile defines the levels of rules, which override the
+// levels of the profile it extends
+type Profile struct {
+	Extends string            `yaml:"extends" json:"extends"`
+	Rules   map[string]string `yaml:"rules" json:"rules"`
+}
+
+// newRuleLevels resolves the levels of the selected profile, either
+// a custom, a built-in or a profile file, and the levels of single rules
+func newRuleLevels(options *Options) (report.RuleLevels, error) {
+	ruleLevels, err := resolveProfile(options, options.Profile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addRuleLevels(ruleLevels, options.RuleLevels); err != nil {
+		return nil, err
+	}
+
+	return ruleLevels, nil
+}
+
+func resolveProfile(options *Options, name string, extendedBy []string) (report.RuleLevels, error) {
+	if name == "" {
+		return make(report.RuleLevels), nil
+	}
+
+	if slices.Contains(extendedBy, name) {
+		return nil, fmt.Errorf("profile %s extends itself", name)
+	}
+
+	profile, exists := options.Profiles[name]
+	if !exists {
+		if ruleLevels, isBuiltIn := report.Profiles[name]; isBuiltIn {
+			return maps.Clone(ruleLevels), nil
+		}
+
+		if extension := filepath.Ext(name); extension != ".yml" && extension != ".yaml" {
+			return nil, fmt.Errorf("unknown profile %s, expected a profile file or one of %v", name, profileNames(options))
+		}
+
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read profile: %w", err)
+		}
+
+		if err := yaml.Unmarshal(content, &profile); err != nil {
+			return nil, fmt.Errorf("unable to parse profile %s: %w", name, err)
+		}
+	}
+
+	ruleLevels, err := resolveProfile(options, profile.Extends, append(extendedBy, name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addRuleLevels(ruleLevels, profile.Rules); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", name, err)
+	}
+
+	return ruleLevels, nil
+}
+
+// addRuleLevels adds the levels to the rule levels, where shellcheck
+// codes may be given with or without their SC prefix
+func addRuleLevels(ruleLevels report.RuleLevels, levels map[string]string) error {
+	for rule, level := range levels {
+		if !slices.Contains(report.Levels, level) {
+			return fmt.Errorf("unknown level %s of rule %s, expected one of %v", level, rule, report.Levels)
+		}
+
+		if _, err := strconv.Atoi(rule); err == nil {
+			rule = "SC" + rule
+		}
+		ruleLevels[strings.ToUpper(rule)] = level
+	}
+
+	return nil
+}
+
+func profileNames(options *Options) []string {
+	names := slices.Collect(maps.Keys(report.Profiles))
+	names = append(names, slices.Collect(maps.Keys(options.Profiles))...)
+	slices.Sort(names)
+	return names
+}
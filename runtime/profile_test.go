@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"testing"
+)
+
+func TestRuleLevels(t *testing.T) {
+	profileFile := filepath.Join(t.TempDir(), "profile.yml")
+	if err := os.WriteFile(profileFile, []byte("extends: security\nrules:\n  '2034': style\n"), 0644); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Profiles = map[string]Profile{
+		"security": {Extends: "recommended", Rules: map[string]string{"SC2029": "error"}},
+		"loop":     {Extends: "loop"},
+	}
+
+	tests := []struct {
+		profile    string
+		ruleLevels map[string]string
+		expected   report.RuleLevels
+	}{
+		{"", nil, report.RuleLevels{}},
+		{"", map[string]string{"sc2086": "error"}, report.RuleLevels{"SC2086": "error"}},
+		{"recommended", map[string]string{"SC2154": "error"}, report.RuleLevels{"SC2034": "info", "SC2154": "error"}},
+		{"security", nil, report.RuleLevels{"SC2029": "error", "SC2034": "info", "SC2154": "info"}},
+		{profileFile, nil, report.RuleLevels{"SC2029": "error", "SC2034": "style", "SC2154": "info"}},
+	}
+
+	for _, test := range tests {
+		options.Profile, options.RuleLevels = test.profile, test.ruleLevels
+		ruleLevels, err := newRuleLevels(options)
+		if err != nil {
+			t.Errorf("unexpected error for profile %s: %s", test.profile, err)
+			continue
+		}
+
+		if len(ruleLevels) != len(test.expected) {
+			t.Errorf("expected rule levels %v of profile %s, got %v", test.expected, test.profile, ruleLevels)
+		}
+		for rule, level := range test.expected {
+			if ruleLevels[rule] != level {
+				t.Errorf("expected rule levels %v of profile %s, got %v", test.expected, test.profile, ruleLevels)
+			}
+		}
+	}
+
+	for _, profile := range []string{"loop", "unknown"} {
+		options.Profile, options.RuleLevels = profile, nil
+		if _, err := newRuleLevels(options); err == nil {
+			t.Errorf("expected error for profile %s", profile)
+		}
+	}
+}
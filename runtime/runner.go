@@ -4,26 +4,38 @@ import (
 	"errors"
 	"fmt"
 	"github.com/bmatcuk/doublestar/v4"
-	"log"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
 	"scriptcheck/color"
 	"scriptcheck/reader"
+	"slices"
+	"strings"
+	"sync"
 )
 
 const StdoutOutput = "stdout"
 
-func collectAndExtractScripts(options *Options, globPatterns []string) ([]reader.ScriptBlock, []string, error) {
-	files, err := collectFiles(globPatterns)
+// collectAndExtractScripts extracts the scripts of all collected files,
+// which are limited to the changed files in case changes are given
+func collectAndExtractScripts(
+	options *Options,
+	globPatterns []string,
+	changes *changeSet,
+) ([]reader.ScriptBlock, []string, error) {
+	files, err := collectFiles(options, globPatterns)
 	if err != nil {
 		return nil, nil, err
 	}
+	files = changes.filterFiles(files)
 
 	if len(files) == 0 && options.Strict {
 		return nil, nil, errors.New("no files found")
 	}
 
-	log.Printf("Reading %s file(s)...\n", color.Color(len(files), color.Bold))
+	options.logger().Printf("Reading %s file(s)...\n", color.Color(len(files), color.Bold))
 	if scripts, err := extractScriptsFromFiles(options, files); err != nil {
-		log.Printf("Error extracting scripts: %v\n", err)
+		options.logger().Printf("Error extracting scripts: %v\n", err)
 		return nil, nil, fmt.Errorf("unable to extract files: %w", err)
 	} else {
 		return scripts, files, nil
@@ -31,39 +43,135 @@ func collectAndExtractScripts(options *Options, globPatterns []string) ([]reader
 }
 
 func extractScriptsFromFiles(options *Options, files []string) ([]reader.ScriptBlock, error) {
-	decoder := reader.NewDecoder(options.PipelineType, options.Debug, options.DefaultShell, options.ExperimentalFolding)
+	decoder, err := newDecoder(options)
+	if err != nil {
+		return nil, err
+	}
+
 	scripts := make([]reader.ScriptBlock, 0)
 
 	if options.Merge {
 		fileScripts, err := decoder.MergeAndDecode(files)
 		if err != nil {
-			log.Printf("Error while merging: %s\n", err.Error())
+			options.logger().Printf("Error while merging: %s\n", err.Error())
 			return nil, err
 		}
 		scripts = append(scripts, fileScripts...)
 	} else {
-		for _, file := range files {
-			fileScripts, err := decoder.DecodeFile(file)
-			if err != nil {
-				log.Printf("Error while running: %s\n", err.Error())
-				return nil, err
-			}
-			scripts = append(scripts, fileScripts...)
+		fileScripts, err := decodeFiles(decoder, options.Jobs, files)
+		if err != nil {
+			options.logger().Printf("Error while running: %s\n", err.Error())
+			return nil, err
 		}
+		scripts = append(scripts, fileScripts...)
 	}
 
+	applyOverrides(options.Overrides, scripts)
+
 	return scripts, nil
 }
 
-func collectFiles(globPatterns []string) ([]string, error) {
+func newDecoder(options *Options) (reader.ScriptDecoder, error) {
+	decoder, err := reader.NewDecoder(options.PipelineType, options.Debug, options.DefaultShell)
+	if err != nil {
+		return reader.ScriptDecoder{}, err
+	}
+
+	decoder = decoder.WithPreludes(options.Preludes).WithLogger(options.logger())
+	return decoder.WithStdin(options.StdinFileName, os.Stdin), nil
+}
+
+// decodeFiles decodes the files concurrently, bounded by the given number
+// of jobs. Scripts are returned in the order of the given files.
+func decodeFiles(decoder reader.ScriptDecoder, jobs int, files []string) ([]reader.ScriptBlock, error) {
+	if jobs <= 0 {
+		jobs = goruntime.NumCPU()
+	}
+
+	fileScripts := make([][]reader.ScriptBlock, len(files))
+	fileErrors := make([]error, len(files))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, jobs)
+	for i, file := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			fileScripts[i], fileErrors[i] = decoder.DecodeFile(file)
+		}()
+	}
+	wg.Wait()
+
+	// report the error of the first failing file
+	for _, err := range fileErrors {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return slices.Concat(fileScripts...), nil
+}
+
+// collectFiles returns all files matching the glob patterns, or the included
+// or discovered files in case no pattern is given. Yaml read from stdin is
+// denoted by the reader.StdinFile, which is added in case it is passed as
+// pattern or a virtual file name for stdin is configured. Files matching
+// a pattern are skipped if vendored or ignored, unless given explicitly.
+func collectFiles(options *Options, globPatterns []string) ([]string, error) {
 	files := make([]string, 0)
+	if options.StdinFileName != "" && !slices.Contains(globPatterns, reader.StdinFile) {
+		files = append(files, reader.StdinFile)
+	}
+
+	if len(globPatterns) == 0 && len(files) == 0 {
+		if len(options.Include) == 0 {
+			return discoverFiles(options)
+		}
+		globPatterns = options.Include
+	}
+
+	ignore := newIgnoreMatcher()
 	for _, pattern := range globPatterns {
+		if pattern == reader.StdinFile {
+			if !slices.Contains(files, reader.StdinFile) {
+				files = append(files, reader.StdinFile)
+			}
+			continue
+		}
+
 		globFiles, err := doublestar.FilepathGlob(pattern)
 		if err != nil {
 			return nil, err
 		}
+
+		if hasGlobMeta(pattern) {
+			globFiles = slices.DeleteFunc(globFiles, func(file string) bool {
+				return ignore.isSkipped(file, false)
+			})
+		}
 		files = append(files, globFiles...)
 	}
 
-	return files, nil
+	return slices.DeleteFunc(files, func(file string) bool {
+		return isExcluded(options.Exclude, file)
+	}), nil
+}
+
+// isExcluded reports whether the file matches any of the exclude patterns
+func isExcluded(patterns []string, file string) bool {
+	if file == reader.StdinFile {
+		return false
+	}
+
+	file = filepath.ToSlash(filepath.Clean(file))
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return doublestar.MatchUnvalidated(pattern, file)
+	})
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
 }
@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"scriptcheck/color"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+)
+
+// suppressionOrigin identifies the directive disabling a rule
+type suppressionOrigin struct {
+	file string
+	line int
+	rule string
+}
+
+// suppressionEntry describes a suppression and
+// all scripts the suppression applies to
+type suppressionEntry struct {
+	origin      suppressionOrigin
+	suppression reader.Suppression
+	scripts     []reader.ScriptBlock
+}
+
+func ListSuppressions(options *Options, globPatterns []string) error {
+	scripts, files, err := collectAndExtractScripts(options, globPatterns, nil)
+	if err != nil {
+		return err
+	}
+
+	entries := collectSuppressions(scripts)
+	options.logger().Printf(
+		"Found %s suppression(s) in %s file(s)...\n",
+		color.Color(len(entries), color.Bold),
+		color.Color(len(files), color.Bold),
+	)
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	writer := NewReportWriter(options)
+	if _, err := io.WriteString(writer, formatSuppressions(entries)); err != nil {
+		_ = writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// collectSuppressions groups suppressions of all scripts by their origin
+// sorted by rule, file and line of the defining directive
+func collectSuppressions(scripts []reader.ScriptBlock) []*suppressionEntry {
+	entryMap := make(map[suppressionOrigin]*suppressionEntry)
+	for _, script := range scripts {
+		for _, suppression := range script.Suppressions() {
+			origin := suppressionOrigin{script.FileName, suppression.Line, suppression.Rule}
+			if entry, exists := entryMap[origin]; exists {
+				entry.scripts = append(entry.scripts, script)
+			} else {
+				entryMap[origin] = &suppressionEntry{origin, suppression, []reader.ScriptBlock{script}}
+			}
+		}
+	}
+
+	entries := make([]*suppressionEntry, 0, len(entryMap))
+	for _, entry := range entryMap {
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b *suppressionEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.origin.rule, b.origin.rule),
+			cmp.Compare(a.origin.file, b.origin.file),
+			cmp.Compare(a.origin.line, b.origin.line),
+		)
+	})
+
+	return entries
+}
+
+func formatSuppressions(entries []*suppressionEntry) string {
+	builder := new(strings.Builder)
+	for index, entry := range entries {
+		if index == 0 || entries[index-1].origin.rule != entry.origin.rule {
+			ruleCount := 0
+			for _, other := range entries {
+				if other.origin.rule == entry.origin.rule {
+					ruleCount++
+				}
+			}
+
+			builder.WriteString(color.Color(fmt.Sprintf("%s: %d suppression(s)", entry.origin.rule, ruleCount), color.Bold))
+			builder.WriteString("\n")
+		}
+
+		jobs := make([]string, 0)
+		for _, script := range entry.scripts {
+			job := fmt.Sprintf("%s (%s)", script.Job, script.Section)
+			if !slices.Contains(jobs, job) {
+				jobs = append(jobs, job)
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("  %s:%d %s", entry.origin.file, entry.origin.line, strings.Join(jobs, ", ")))
+		if entry.suppression.Reason != "" {
+			builder.WriteString(fmt.Sprintf(" -- %s", entry.suppression.Reason))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// missingReasonReports creates reports for every suppression
+// not providing a justification
+func missingReasonReports(scripts []reader.ScriptBlock) []report.ScriptCheckReport {
+	problems := make([]reader.DirectiveProblem, 0)
+	for _, entry := range collectSuppressions(scripts) {
+		if entry.suppression.Reason == "" {
+			problems = append(problems, reader.DirectiveProblem{
+				File:    entry.origin.file,
+				Path:    entry.scripts[0].Path,
+				Line:    entry.origin.line,
+				Level:   reader.DirectiveLevelError,
+				Code:    reader.DirectiveMissingReason,
+				Message: fmt.Sprintf("disabling %s requires a justification using reason=\"...\"", entry.origin.rule),
+			})
+		}
+	}
+
+	return report.NewDirectiveReports(problems)
+}
+
+// unusedSuppressionReports checks all scripts defining suppressions again
+// without those suppressions and creates reports for every suppression
+// which did not suppress any finding in any of its scripts
+func unusedSuppressionReports(ctx context.Context, options *Options, scripts []reader.ScriptBlock) ([]report.ScriptCheckReport, error) {
+	entries := collectSuppressions(scripts)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	suppressedScripts := slices.DeleteFunc(slices.Clone(scripts), func(script reader.ScriptBlock) bool {
+		return len(script.Suppressions()) == 0
+	})
+
+	unsuppressedScripts := make([]reader.ScriptBlock, 0, len(suppressedScripts))
+	for _, script := range suppressedScripts {
+		unsuppressedScripts = append(unsuppressedScripts, script.WithoutSuppressions())
+	}
+
+	if options.Debug {
+		options.logger().Printf(
+			"Checking %s script(s) without suppressions...\n",
+			color.Color(len(unsuppressedScripts), color.Bold),
+		)
+	}
+
+	unsuppressedReports := make([]report.ScriptCheckReport, 0)
+	err := runCheckers(ctx, options, unsuppressedScripts, func(reports []report.ScriptCheckReport) error {
+		unsuppressedReports = append(unsuppressedReports, reports...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]reader.DirectiveProblem, 0)
+	for _, entry := range entries {
+		isUsed := slices.ContainsFunc(unsuppressedReports, func(scriptReport report.ScriptCheckReport) bool {
+			if entry.origin.rule != "all" && entry.origin.rule != scriptReport.Reason {
+				return false
+			}
+
+			return slices.ContainsFunc(entry.scripts, func(script reader.ScriptBlock) bool {
+				return isSameScriptBlock(script, scriptReport.Script)
+			})
+		})
+
+		if !isUsed {
+			problems = append(problems, reader.DirectiveProblem{
+				File:    entry.origin.file,
+				Path:    entry.scripts[0].Path,
+				Line:    entry.origin.line,
+				Level:   reader.DirectiveLevelWarning,
+				Code:    reader.DirectiveUnusedSuppression,
+				Message: fmt.Sprintf("disable=%s does not suppress any finding", entry.origin.rule),
+			})
+		}
+	}
+
+	return report.NewDirectiveReports(problems), nil
+}
+
+func isSameScriptBlock(a, b reader.ScriptBlock) bool {
+	return a.FileName == b.FileName && a.BlockName == b.BlockName && a.StartPos == b.StartPos
+}
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"context"
+	"scriptcheck/reader"
+	"testing"
+)
+
+func TestUnusedSuppressionReports(t *testing.T) {
+	cases := []struct {
+		name      string
+		content   string
+		overrides []Override
+		expected  []string
+	}{
+		{
+			name:     "used suppression below severity",
+			content:  "job:\n  script:\n    # scriptcheck severity=warning disable=SC2086\n    - echo $A\n",
+			expected: []string{},
+		},
+		{
+			name:      "rule disabled by override",
+			content:   "job:\n  script:\n    # scriptcheck disable=all\n    - echo $A\n",
+			overrides: []Override{{Disable: []string{"SC2086"}}},
+			expected:  []string{"disable=all does not suppress any finding"},
+		},
+	}
+
+	for _, c := range cases {
+		options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+		options.Overrides = c.overrides
+		scripts, err := DecodeInputs(context.Background(), options, []Input{{Name: "test.yml", Content: []byte(c.content)}})
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		reports, err := unusedSuppressionReports(context.Background(), options, scripts)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		if len(reports) != len(c.expected) {
+			t.Fatalf("%s: expected %d reports, got %v", c.name, len(c.expected), reports)
+		}
+		for i, scriptReport := range reports {
+			if scriptReport.Message != c.expected[i] {
+				t.Errorf("%s: expected message %q, got %q", c.name, c.expected[i], scriptReport.Message)
+			}
+		}
+	}
+}
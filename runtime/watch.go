@@ -0,0 +1,342 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bmatcuk/doublestar/v4"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"scriptcheck/color"
+	"scriptcheck/format"
+	"scriptcheck/reader"
+	"scriptcheck/report"
+	"slices"
+	"strings"
+	"time"
+)
+
+// interval between polls of the watched files and the time without
+// further changes awaited before checking, covering bursts of saves
+const (
+	watchInterval = 500 * time.Millisecond
+	watchDebounce = 300 * time.Millisecond
+)
+
+// clears the terminal and moves the cursor to the top left corner
+const clearScreen = "\033[H\033[2J"
+
+// WatchFiles checks the matched files and all local files they include,
+// and re-checks every file whenever it changes until interrupted
+func WatchFiles(options *Options, globPatterns []string) error {
+	if options.Fix || options.Diff || options.Merge || options.UpdateBaseline || options.ChangedSince != "" {
+		return errors.New("unable to watch files while fixing, merging, updating the baseline or checking changes")
+	}
+
+	if options.StdinFileName != "" || slices.Contains(globPatterns, reader.StdinFile) {
+		return errors.New("unable to watch yaml read from stdin")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	watcher, err := newScriptWatcher(options, globPatterns, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for initial := true; ; initial = false {
+		changedFiles, err := watcher.poll()
+		if err != nil {
+			return err
+		}
+
+		if initial || len(changedFiles) > 0 {
+			// wait until files stopped changing
+			for !initial {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(watchDebounce):
+				}
+				moreFiles, err := watcher.poll()
+				if err != nil {
+					return err
+				}
+				if len(moreFiles) == 0 {
+					break
+				}
+				changedFiles = append(changedFiles, moreFiles...)
+			}
+
+			if err := watcher.check(ctx, changedFiles); err != nil {
+				if ctx.Err() != nil {
+					// interrupted while checking
+					return nil
+				}
+				return err
+			}
+
+			if err := watcher.redraw(); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fileState identifies the content of a file without reading it
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// scriptWatcher keeps the reports of all checked files and re-checks
+// files whose content changed since they were checked the last time
+type scriptWatcher struct {
+	options      *Options
+	globPatterns []string
+	decoder      reader.ScriptDecoder
+	writer       io.Writer
+
+	// state of all watched files when they were polled the last time
+	states map[string]fileState
+
+	// reports and errors of the checked files
+	reports map[string][]report.ScriptCheckReport
+	errors  map[string]error
+
+	// local files included by the checked files
+	includes map[string][]string
+}
+
+func newScriptWatcher(options *Options, globPatterns []string, writer io.Writer) (*scriptWatcher, error) {
+	decoder, err := newDecoder(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scriptWatcher{
+		options:      options,
+		globPatterns: globPatterns,
+		decoder:      decoder,
+		writer:       writer,
+		states:       make(map[string]fileState),
+		reports:      make(map[string][]report.ScriptCheckReport),
+		errors:       make(map[string]error),
+		includes:     make(map[string][]string),
+	}, nil
+}
+
+// files returns the files to check, which are the matched files
+// as well as all files included by any of them
+func (w *scriptWatcher) files() ([]string, error) {
+	files, err := collectFiles(w.options, w.globPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(files); i++ {
+		for _, include := range w.includes[files[i]] {
+			if !slices.Contains(files, include) {
+				files = append(files, include)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// preludeFiles returns the shell files prepended to every script
+func (w *scriptWatcher) preludeFiles() []string {
+	return slices.DeleteFunc(slices.Clone(w.options.Preludes), func(prelude string) bool {
+		return strings.HasPrefix(prelude, "*") || strings.HasPrefix(prelude, "job:")
+	})
+}
+
+// poll returns all files to check which got created, changed or removed
+// since the last poll, all files are returned in case a prelude changed
+func (w *scriptWatcher) poll() ([]string, error) {
+	files, err := w.files()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]fileState)
+	changedFiles := make([]string, 0)
+	preludeChanged := false
+	for _, file := range slices.Concat(files, w.preludeFiles()) {
+		previous, existed := w.states[file]
+		info, err := os.Stat(file)
+		if err != nil {
+			if !existed {
+				continue
+			}
+		} else {
+			states[file] = fileState{info.ModTime(), info.Size()}
+		}
+
+		if !existed || previous != states[file] {
+			if slices.Contains(files, file) {
+				changedFiles = append(changedFiles, file)
+			} else {
+				preludeChanged = true
+			}
+		}
+	}
+
+	for file := range w.reports {
+		if !slices.Contains(files, file) {
+			changedFiles = append(changedFiles, file)
+		}
+	}
+	w.states = states
+
+	if preludeChanged {
+		return files, nil
+	}
+
+	return changedFiles, nil
+}
+
+// check re-checks the given files, files which no longer exist or match
+// are removed. Results of unchanged scripts are reused from the cache.
+// Checking stops once the context is done, returning its error.
+func (w *scriptWatcher) check(ctx context.Context, files []string) error {
+	current, err := w.files()
+	if err != nil {
+		return err
+	}
+
+	scripts := make([]reader.ScriptBlock, 0)
+	checkedFiles := make([]string, 0)
+	for _, file := range slices.Compact(slices.Sorted(slices.Values(files))) {
+		delete(w.reports, file)
+		delete(w.errors, file)
+		delete(w.includes, file)
+
+		content, err := os.ReadFile(file)
+		if err != nil || !slices.Contains(current, file) {
+			continue
+		}
+		w.includes[file] = w.resolveIncludes(content)
+
+		fileScripts, err := decodeFiles(w.decoder, w.options.Jobs, []string{file})
+		if err != nil {
+			w.errors[file] = err
+			continue
+		}
+
+		scripts = append(scripts, fileScripts...)
+		checkedFiles = append(checkedFiles, file)
+		w.reports[file] = make([]report.ScriptCheckReport, 0)
+	}
+	applyOverrides(w.options.Overrides, scripts)
+
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	err = runCheckers(ctx, w.options, scripts, func(reports []report.ScriptCheckReport) error {
+		for _, scriptReport := range reports {
+			w.reports[scriptReport.File] = append(w.reports[scriptReport.File], scriptReport)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		for _, file := range checkedFiles {
+			delete(w.reports, file)
+			w.errors[file] = err
+		}
+	}
+
+	return nil
+}
+
+// resolveIncludes returns the existing local files included by the
+// pipeline, which are relative to the root of the repository
+func (w *scriptWatcher) resolveIncludes(content []byte) []string {
+	root := repositoryRoot()
+
+	includes := make([]string, 0)
+	for _, pattern := range reader.LocalIncludes(w.options.PipelineType, content) {
+		pattern = filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(pattern, "/")))
+		files, err := doublestar.FilepathGlob(filepath.ToSlash(pattern))
+		if err != nil {
+			continue
+		}
+		includes = append(includes, files...)
+	}
+
+	return includes
+}
+
+// redraw clears the terminal and prints the reports of all files
+func (w *scriptWatcher) redraw() error {
+	files := slices.Sorted(maps.Keys(w.reports))
+	reports := make([]report.ScriptCheckReport, 0)
+	for _, file := range files {
+		reports = append(reports, w.reports[file]...)
+	}
+	report.SortReports(reports)
+
+	baseline, err := newBaseline(w.options)
+	if err != nil {
+		return err
+	}
+	if baseline != nil {
+		reports = baseline.filter(reports)
+	}
+
+	if _, err := io.WriteString(w.writer, clearScreen); err != nil {
+		return fmt.Errorf("unable to write shellcheck output: %w", err)
+	}
+
+	if err := format.WriteReports(&format.PrettyFormatter{}, w.writer, reports); err != nil {
+		return fmt.Errorf("unable to write shellcheck output: %w", err)
+	}
+
+	for _, file := range slices.Sorted(maps.Keys(w.errors)) {
+		w.options.logger().Printf("Unable to check %s: %v", color.Color(file, color.Bold), w.errors[file])
+	}
+
+	w.options.logger().Printf(
+		"Found %s issue(s) in %s file(s) at %s, watching for changes...",
+		color.Color(len(reports), color.Bold),
+		color.Color(len(files), color.Bold),
+		time.Now().Format(time.TimeOnly),
+	)
+
+	return nil
+}
+
+// repositoryRoot returns the root of the git repository containing
+// the working directory, or the working directory itself
+func repositoryRoot() string {
+	directory := "."
+	for {
+		if _, err := os.Stat(filepath.Join(directory, ".git")); err == nil {
+			return directory
+		}
+
+		absolute, err := filepath.Abs(directory)
+		if err != nil || filepath.Dir(absolute) == absolute {
+			return "."
+		}
+		directory = filepath.Join(directory, "..")
+	}
+}
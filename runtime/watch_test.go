@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"scriptcheck/reader"
+	"slices"
+	"testing"
+)
+
+func TestScriptWatcher(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(workingDirectory) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	writeFile := func(file, content string) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+
+	writeFile(".git/HEAD", "")
+	writeFile("pipeline.yml", "include:\n  - local: /ci/included.yml\njob:\n  script: echo\n")
+	writeFile(filepath.Join("ci", "included.yml"), "included:\n  script: echo\n")
+
+	options := newOptionsWithDefaults(reader.PipelineTypeGitlab)
+	options.Checker = CheckerParse
+	watcher, err := newScriptWatcher(options, []string{"pipeline.yml"}, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	poll := func(expected ...string) {
+		changedFiles, err := watcher.poll()
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		slices.Sort(changedFiles)
+		if !slices.Equal(changedFiles, expected) {
+			t.Errorf("expected changed files %v, got %v", expected, changedFiles)
+		}
+
+		if err := watcher.check(context.Background(), changedFiles); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+
+	included := filepath.Join("ci", "included.yml")
+	poll("pipeline.yml")
+	// includes are watched once the including file got checked
+	poll(included)
+	poll()
+
+	writeFile(included, "included:\n  script: echo 'unterminated\n")
+	poll(included)
+	if len(watcher.reports[included]) != 1 {
+		t.Errorf("expected a report of the changed file, got %v", watcher.reports[included])
+	}
+
+	writeFile("pipeline.yml", "job:\n  script: echo\n")
+	poll("pipeline.yml")
+	poll(included)
+	if _, exists := watcher.reports[included]; exists {
+		t.Errorf("expected file no longer included to be removed")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := watcher.check(canceled, []string{"pipeline.yml"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected checking to be canceled, got %v", err)
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"scriptcheck/reader"
 )
 
-func NewReportWriter(options *Options) io.StringWriter {
+// NewReportWriter returns the writer for the configured output. Output
+// files get created with the first write, thus runs without any output
+// do not touch an existing file.
+func NewReportWriter(options *Options) io.WriteCloser {
 	switch options.OutputFile {
 	case StdoutOutput:
 		return &StdoutWriter{}
@@ -87,25 +90,37 @@ func (w *TempScriptWriter) WriteScript(script reader.ScriptBlock) (*os.File, err
 
 type StdoutWriter struct{}
 
-func (StdoutWriter) WriteString(s string) (int, error) {
-	return os.Stdout.WriteString(s)
+func (StdoutWriter) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (StdoutWriter) Close() error {
+	return nil
 }
 
 type FileWriter struct {
 	fileName string
+	file     *os.File
 }
 
-func (writer FileWriter) WriteString(s string) (int, error) {
-	file, err := os.Create(writer.fileName)
-	if err != nil {
-		return 0, err
+func (writer *FileWriter) Write(p []byte) (int, error) {
+	if writer.file == nil {
+		file, err := os.Create(writer.fileName)
+		if err != nil {
+			return 0, err
+		}
+		writer.file = file
 	}
 
-	defer func() {
-		err = file.Close()
-	}()
+	return writer.file.Write(p)
+}
+
+func (writer *FileWriter) Close() error {
+	if writer.file == nil {
+		return nil
+	}
 
-	return file.WriteString(s)
+	return writer.file.Close()
 }
 
 func writeScriptBlock(writer io.StringWriter, script reader.ScriptBlock) error {